@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AdminGetUsers godoc
+// @Summary      Get Users
+// @Description  searches users by email or name with pagination
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        q     query    string  false  "Search in email and name"
+// @Param        page  query    string  false  "Switch page by 'page'"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /admin/users [get]
+// @Security     ApiKeyAuth
+func AdminGetUsers(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	pageQuery := c.DefaultQuery("page", "0")
+	page, _ := strconv.Atoi(pageQuery)
+	limit := 20
+
+	users, err := services.GetUsers(query, page, limit)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	hasPrev := page > 0
+	hasNext := len(users) > limit
+
+	if hasNext {
+		users = users[:limit]
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"users": users, "prev": hasPrev, "next": hasNext}
+	response.SendResponse(c)
+}
+
+// AdminGetOneUser godoc
+// @Summary      Get a user
+// @Description  gets user by id with note count and active sessions
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /admin/users/{id} [get]
+// @Security     ApiKeyAuth
+func AdminGetOneUser(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, _ := primitive.ObjectIDFromHex(c.Param("id"))
+	user, err := services.FindUserById(userId)
+	if err != nil {
+		response.StatusCode = http.StatusNotFound
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	noteCount, err := services.CountNotes(user.ID)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	sessions, err := services.GetActiveSessions(user.ID)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	sessionList := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		sessionList = append(sessionList, session.GetSessionJson(false))
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"user": user, "notes": noteCount, "sessions": sessionList}
+	response.SendResponse(c)
+}
+
+// AdminDisableUser godoc
+// @Summary      Disable a user
+// @Description  disables a user and revokes its tokens
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Router       /admin/users/{id}/disable [put]
+// @Security     ApiKeyAuth
+func AdminDisableUser(c *gin.Context) {
+	setUserDisabled(c, true)
+}
+
+// AdminEnableUser godoc
+// @Summary      Enable a user
+// @Description  enables a disabled user
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Router       /admin/users/{id}/enable [put]
+// @Security     ApiKeyAuth
+func AdminEnableUser(c *gin.Context) {
+	setUserDisabled(c, false)
+}
+
+func setUserDisabled(c *gin.Context, disabled bool) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	user, ok := getOtherUser(c, response)
+	if !ok {
+		return
+	}
+
+	err := services.SetUserDisabled(user, disabled)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"user": user}
+	response.SendResponse(c)
+}
+
+// AdminLogoutUser godoc
+// @Summary      Logout a user
+// @Description  revokes every token and api key of a user
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Router       /admin/users/{id}/logout [post]
+// @Security     ApiKeyAuth
+func AdminLogoutUser(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	user, ok := getOtherUser(c, response)
+	if !ok {
+		return
+	}
+
+	err := services.BlacklistUserTokens(user.ID)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	err = services.RevokeUserApiKeys(user.ID)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// AdminSetUserRole godoc
+// @Summary      Set role of a user
+// @Description  changes role of a user, user has to login again
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Param        req  body      models.SetRoleRequest true "Set Role Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Router       /admin/users/{id}/role [put]
+// @Security     ApiKeyAuth
+func AdminSetUserRole(c *gin.Context) {
+	var requestBody models.SetRoleRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	user, ok := getOtherUser(c, response)
+	if !ok {
+		return
+	}
+
+	err := services.SetUserRole(user, requestBody.Role)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"user": user}
+	response.SendResponse(c)
+}
+
+// AdminDeleteUser godoc
+// @Summary      Delete a user
+// @Description  deletes a user with its notes, notebooks and tokens
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Router       /admin/users/{id} [delete]
+// @Security     ApiKeyAuth
+func AdminDeleteUser(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	user, ok := getOtherUser(c, response)
+	if !ok {
+		return
+	}
+
+	err := services.DeleteUser(user.ID)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// getOtherUser finds the user in path, users can manage only users with a lower role and not their own account here
+func getOtherUser(c *gin.Context, response *models.Response) (*db.User, bool) {
+	userId, _ := primitive.ObjectIDFromHex(c.Param("id"))
+	if currentUserId, _ := c.Get("userId"); currentUserId == userId {
+		response.Message = "you cannot manage your own account"
+		response.SendResponse(c)
+		return nil, false
+	}
+
+	user, err := services.FindUserById(userId)
+	if err != nil {
+		response.StatusCode = http.StatusNotFound
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return nil, false
+	}
+
+	if !db.RoleOutranks(c.GetString("userRole"), user.Role) {
+		response.StatusCode = http.StatusForbidden
+		response.Message = "you cannot manage users with the same or a higher role"
+		response.SendResponse(c)
+		return nil, false
+	}
+
+	return user, true
+}
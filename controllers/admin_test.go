@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// adminTestRouter serves admin handlers as if the caller is authenticated with the role
+func adminTestRouter(callerId primitive.ObjectID, role string) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userId", callerId)
+		c.Set("userRole", role)
+	})
+	router.PUT("/users/:id/disable", AdminDisableUser)
+	router.POST("/users/:id/logout", AdminLogoutUser)
+
+	return router
+}
+
+func createTestUser(t *testing.T, email string, role string) *db.User {
+	t.Helper()
+
+	user := &db.User{Email: email, Name: "Test", Role: role}
+	if err := mgm.Coll(user).Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+func TestAdminDisableUserRoles(t *testing.T) {
+	useTestDatabase(t)
+
+	tests := []struct {
+		name       string
+		callerRole string
+		targetRole string
+		status     int
+	}{
+		{name: "moderator disables admin", callerRole: db.RoleModerator, targetRole: db.RoleAdmin, status: http.StatusForbidden},
+		{name: "moderator disables moderator", callerRole: db.RoleModerator, targetRole: db.RoleModerator, status: http.StatusForbidden},
+		{name: "admin disables admin", callerRole: db.RoleAdmin, targetRole: db.RoleAdmin, status: http.StatusForbidden},
+		{name: "moderator disables user", callerRole: db.RoleModerator, targetRole: db.RoleUser, status: http.StatusOK},
+		{name: "admin disables moderator", callerRole: db.RoleAdmin, targetRole: db.RoleModerator, status: http.StatusOK},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := createTestUser(t, "target"+string(rune('a'+i))+"@example.com", test.targetRole)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, "/users/"+target.ID.Hex()+"/disable", nil)
+			adminTestRouter(primitive.NewObjectID(), test.callerRole).ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Fatalf("expected %d, got %d: %s", test.status, recorder.Code, recorder.Body.String())
+			}
+
+			saved, err := services.FindUserById(target.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.Disabled != (test.status == http.StatusOK) {
+				t.Fatalf("unexpected disabled state %v", saved.Disabled)
+			}
+		})
+	}
+}
+
+func TestAdminLogoutUserChecksTarget(t *testing.T) {
+	useTestDatabase(t)
+
+	moderator := createTestUser(t, "moderator@example.com", db.RoleModerator)
+	admin := createTestUser(t, "admin@example.com", db.RoleAdmin)
+	router := adminTestRouter(moderator.ID, db.RoleModerator)
+
+	tests := map[string]struct {
+		id     string
+		status int
+	}{
+		"malformed id": {id: "not-an-id", status: http.StatusNotFound},
+		"own account":  {id: moderator.ID.Hex(), status: http.StatusBadRequest},
+		"higher role":  {id: admin.ID.Hex(), status: http.StatusForbidden},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/users/"+test.id+"/logout", nil))
+
+			if recorder.Code != test.status {
+				t.Fatalf("expected %d, got %d: %s", test.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+)
+
+// CreateApiKey godoc
+// @Summary      Create Api Key
+// @Description  creates a scoped api key for machine clients, the key is returned only once
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.CreateApiKeyRequest true "Create Api Key Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /users/me/api-keys [post]
+// @Security     ApiKeyAuth
+func CreateApiKey(c *gin.Context) {
+	var requestBody models.CreateApiKeyRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	user, err := services.FindUserById(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	name := strings.TrimSpace(requestBody.Name)
+	apiKey, err := services.CreateApiKey(user, name, requestBody.Scopes, requestBody.ExpiresInDays)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusCreated
+	response.Success = true
+	response.Data = gin.H{"api_key": apiKey}
+	response.SendResponse(c)
+}
+
+// GetApiKeys godoc
+// @Summary      Get Api Keys
+// @Description  lists api keys of the user
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /users/me/api-keys [get]
+// @Security     ApiKeyAuth
+func GetApiKeys(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	apiKeys, err := services.GetApiKeys(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"api_keys": apiKeys}
+	response.SendResponse(c)
+}
+
+// RevokeApiKey godoc
+// @Summary      Revoke Api Key
+// @Description  revokes an api key by id
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Api Key ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /users/me/api-keys/{id} [delete]
+// @Security     ApiKeyAuth
+func RevokeApiKey(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	apiKeyId, _ := primitive.ObjectIDFromHex(c.Param("id"))
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	err := services.RevokeApiKey(userId.(primitive.ObjectID), apiKeyId)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
@@ -6,8 +6,10 @@ import (
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
-	"golang.org/x/crypto/bcrypt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +20,7 @@ import (
 // @Accept       json
 // @Produce      json
 // @Param        req  body      models.RegisterRequest true "Register Request"
+// @Param        X-Device-Name  header  string  false  "Device name shown in sessions"
 // @Success      200  {object}  models.Response
 // @Failure      400  {object}  models.Response
 // @Router       /auth/register [post]
@@ -47,8 +50,18 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// send verification email in background
+	go services.SendVerificationMail(user)
+
 	// generate access tokens
-	accessToken, refreshToken, err := services.GenerateAccessTokens(user)
+	accessToken, refreshToken, err := services.GenerateAccessTokens(user, getTokenDevice(c))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	tokens, err := getTokenResponse(c, accessToken, refreshToken)
 	if err != nil {
 		response.Message = err.Error()
 		response.SendResponse(c)
@@ -58,23 +71,23 @@ func Register(c *gin.Context) {
 	response.StatusCode = http.StatusCreated
 	response.Success = true
 	response.Data = gin.H{
-		"user": user,
-		"token": gin.H{
-			"access":  accessToken.GetResponseJson(),
-			"refresh": refreshToken.GetResponseJson()},
+		"user":  user,
+		"token": tokens,
 	}
 	response.SendResponse(c)
 }
 
 // Login godoc
 // @Summary      Login
-// @Description  login a user
+// @Description  login a user, returns an mfa challenge instead of tokens if mfa is enabled
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        req  body      models.LoginRequest true "Login Request"
+// @Param        X-Device-Name  header  string  false  "Device name shown in sessions"
 // @Success      200  {object}  models.Response
 // @Failure      400  {object}  models.Response
+// @Failure      429  {object}  models.Response
 // @Router       /auth/login [post]
 func Login(c *gin.Context) {
 	var requestBody models.LoginRequest
@@ -85,24 +98,64 @@ func Login(c *gin.Context) {
 		Success:    false,
 	}
 
-	// get user by email
-	user, err := services.FindUserByEmail(requestBody.Email)
+	// reject locked out email or ip, the attempt counts as failed until the password is verified
+	wait, err := services.ReserveLoginAttempt(requestBody.Email, c.ClientIP())
 	if err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		response.StatusCode = http.StatusTooManyRequests
 		response.Message = err.Error()
 		response.SendResponse(c)
 		return
 	}
 
-	// check hashed password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.Password))
+	// get user by email and check hashed password,
+	// unknown email and wrong password get the same error
+	user, err := services.FindUserByEmail(requestBody.Email)
 	if err != nil {
+		services.CheckDummyPassword(requestBody.Password)
+	}
+
+	if err != nil || !services.CheckUserPassword(user, requestBody.Password) {
 		response.Message = "email and password don't match"
 		response.SendResponse(c)
 		return
 	}
 
+	services.ReleaseLoginAttempt(requestBody.Email, c.ClientIP())
+	services.RehashUserPassword(user, requestBody.Password)
+
+	if user.Disabled {
+		response.StatusCode = http.StatusForbidden
+		response.Message = "account is disabled"
+		response.SendResponse(c)
+		return
+	}
+
+	// users with mfa get a challenge token instead of access tokens
+	if user.MFAEnabled {
+		challenge, err := services.CreateMFAChallenge(user)
+		if err != nil {
+			response.Message = err.Error()
+			response.SendResponse(c)
+			return
+		}
+
+		response.StatusCode = http.StatusOK
+		response.Success = true
+		response.Data = gin.H{"mfa_required": true, "mfa_token": challenge.GetResponseJson()}
+		response.SendResponse(c)
+		return
+	}
+
 	// generate new access tokens
-	accessToken, refreshToken, err := services.GenerateAccessTokens(user)
+	accessToken, refreshToken, err := services.GenerateAccessTokens(user, getTokenDevice(c))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	tokens, err := getTokenResponse(c, accessToken, refreshToken)
 	if err != nil {
 		response.Message = err.Error()
 		response.SendResponse(c)
@@ -112,35 +165,37 @@ func Login(c *gin.Context) {
 	response.StatusCode = http.StatusOK
 	response.Success = true
 	response.Data = gin.H{
-		"user": user,
-		"token": gin.H{
-			"access":  accessToken.GetResponseJson(),
-			"refresh": refreshToken.GetResponseJson()},
+		"user":  user,
+		"token": tokens,
 	}
 	response.SendResponse(c)
 }
 
 // Refresh godoc
 // @Summary      Refresh
-// @Description  refreshes a user token
+// @Description  rotates a refresh token, reusing a rotated token revokes the session
 // @Tags         auth
 // @Accept       json
 // @Produce      json
-// @Param        req  body      models.RefreshRequest true "Refresh Request"
+// @Param        req  body      models.RefreshRequest false "Refresh Request, token is read from cookie in cookie mode"
+// @Param        X-Device-Name  header  string  false  "Device name shown in sessions"
 // @Success      200  {object}  models.Response
 // @Failure      400  {object}  models.Response
 // @Router       /auth/refresh [post]
 func Refresh(c *gin.Context) {
 	var requestBody models.RefreshRequest
 	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+	if requestBody.Token == "" {
+		requestBody.Token = services.GetTokenCookie(c, services.RefreshTokenCookie)
+	}
 
 	response := &models.Response{
 		StatusCode: http.StatusBadRequest,
 		Success:    false,
 	}
 
-	// check token validity
-	token, err := services.VerifyToken(requestBody.Token, db.TokenTypeRefresh)
+	// check token validity, reused tokens revoke their family
+	token, err := services.VerifyRefreshToken(requestBody.Token)
 	if err != nil {
 		response.Message = err.Error()
 		response.SendResponse(c)
@@ -154,22 +209,423 @@ func Refresh(c *gin.Context) {
 		return
 	}
 
-	// delete old token
-	err = services.DeleteTokenById(token.ID)
+	if user.Disabled {
+		response.StatusCode = http.StatusForbidden
+		response.Message = "account is disabled"
+		response.SendResponse(c)
+		return
+	}
+
+	// rotate the presented refresh token within its family
+	accessToken, refreshToken, err := services.RotateAccessTokens(user, token, getTokenDevice(c))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	tokens, err := getTokenResponse(c, accessToken, refreshToken)
 	if err != nil {
 		response.Message = err.Error()
 		response.SendResponse(c)
 		return
 	}
 
-	accessToken, refreshToken, err := services.GenerateAccessTokens(user)
 	response.StatusCode = http.StatusOK
 	response.Success = true
 	response.Data = gin.H{
-		"user": user,
-		"token": gin.H{
-			"access":  accessToken.GetResponseJson(),
-			"refresh": refreshToken.GetResponseJson()},
+		"user":  user,
+		"token": tokens,
+	}
+	response.SendResponse(c)
+}
+
+// VerifyEmail godoc
+// @Summary      Verify Email
+// @Description  verifies user email with the token sent by email
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.VerifyEmailRequest true "Verify Email Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/verify-email [post]
+func VerifyEmail(c *gin.Context) {
+	var requestBody models.VerifyEmailRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	user, err := services.VerifyUserMail(requestBody.Token)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"user": user}
+	response.SendResponse(c)
+}
+
+// ResendVerificationEmail godoc
+// @Summary      Resend Verification Email
+// @Description  sends a new verification email, throttled per user
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Router       /auth/verify-email/resend [post]
+// @Security     ApiKeyAuth
+func ResendVerificationEmail(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	user, err := services.FindUserById(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	err = services.ResendVerificationMail(user)
+	if err != nil {
+		if err == services.ErrMailThrottled {
+			response.StatusCode = http.StatusTooManyRequests
+		}
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// MagicLink godoc
+// @Summary      Magic Link
+// @Description  sends a single use login link, always succeeds to not reveal registered emails
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.MagicLinkRequest true "Magic Link Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/magic-link [post]
+func MagicLink(c *gin.Context) {
+	var requestBody models.MagicLinkRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	// mail is sent in background, so response time doesn't depend on the email
+	go services.SendMagicLinkMail(requestBody.Email)
+
+	response := &models.Response{
+		StatusCode: http.StatusOK,
+		Success:    true,
+		Message:    "if the email is registered, a login link has been sent",
 	}
 	response.SendResponse(c)
 }
+
+// VerifyMagicLink godoc
+// @Summary      Verify Magic Link
+// @Description  logs in with the emailed link token, returns the same response as login
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.MagicLinkVerifyRequest true "Magic Link Verify Request"
+// @Param        X-Device-Name  header  string  false  "Device name shown in sessions"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/magic-link/verify [post]
+func VerifyMagicLink(c *gin.Context) {
+	var requestBody models.MagicLinkVerifyRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	user, err := services.UseMagicLink(requestBody.Token)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	services.ResetLoginFailures(user.Email)
+
+	if user.Disabled {
+		response.StatusCode = http.StatusForbidden
+		response.Message = "account is disabled"
+		response.SendResponse(c)
+		return
+	}
+
+	// link replaces the password, mfa is still required
+	if user.MFAEnabled {
+		challenge, err := services.CreateMFAChallenge(user)
+		if err != nil {
+			response.Message = err.Error()
+			response.SendResponse(c)
+			return
+		}
+
+		response.StatusCode = http.StatusOK
+		response.Success = true
+		response.Data = gin.H{"mfa_required": true, "mfa_token": challenge.GetResponseJson()}
+		response.SendResponse(c)
+		return
+	}
+
+	accessToken, refreshToken, err := services.GenerateAccessTokens(user, getTokenDevice(c))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	tokens, err := getTokenResponse(c, accessToken, refreshToken)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{
+		"user":  user,
+		"token": tokens,
+	}
+	response.SendResponse(c)
+}
+
+// ForgotPassword godoc
+// @Summary      Forgot Password
+// @Description  sends a password reset email, always succeeds to not reveal registered emails
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.ForgotPasswordRequest true "Forgot Password Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/forgot-password [post]
+func ForgotPassword(c *gin.Context) {
+	var requestBody models.ForgotPasswordRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	// mail is sent in background, so response time doesn't depend on the email
+	go services.SendPasswordResetMail(requestBody.Email)
+
+	response := &models.Response{
+		StatusCode: http.StatusOK,
+		Success:    true,
+		Message:    "if the email is registered, a password reset link has been sent",
+	}
+	response.SendResponse(c)
+}
+
+// ResetPassword godoc
+// @Summary      Reset Password
+// @Description  sets a new password with the emailed token and revokes all sessions
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.ResetPasswordRequest true "Reset Password Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/reset-password [post]
+func ResetPassword(c *gin.Context) {
+	var requestBody models.ResetPasswordRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	err := services.ResetPassword(requestBody.Token, requestBody.Password)
+	if err != nil {
+		models.SendValidationErrorResponse(c, err)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// ConfirmEmailChange godoc
+// @Summary      Confirm Email Change
+// @Description  changes user email with the token sent to the new email
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.ConfirmEmailChangeRequest true "Confirm Email Change Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/change-email/confirm [post]
+func ConfirmEmailChange(c *gin.Context) {
+	var requestBody models.ConfirmEmailChangeRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	user, err := services.ConfirmEmailChange(requestBody.Token)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"user": user}
+	response.SendResponse(c)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  revokes the current access token and the refresh token of the same session
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.LogoutRequest false "Logout Request, token is read from cookie in cookie mode"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/logout [post]
+// @Security     ApiKeyAuth
+func Logout(c *gin.Context) {
+	var requestBody models.LogoutRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+	if requestBody.Token == "" {
+		requestBody.Token = services.GetTokenCookie(c, services.RefreshTokenCookie)
+	}
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	tokenId, exists := c.Get("tokenId")
+	if !exists {
+		response.Message = "cannot get token"
+		response.SendResponse(c)
+		return
+	}
+
+	// refresh token must belong to the same session, so other sessions cannot be logged out with it
+	tokenFamily, _ := c.Get("tokenFamily")
+	refreshToken, err := services.VerifyToken(requestBody.Token, db.TokenTypeRefresh)
+	if err != nil || refreshToken.User != userId.(primitive.ObjectID) || refreshToken.Family != tokenFamily {
+		response.Message = "not valid refresh token"
+		response.SendResponse(c)
+		return
+	}
+
+	err = services.BlacklistTokens(userId.(primitive.ObjectID), tokenId.(primitive.ObjectID), refreshToken.ID)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	if services.Config.CookieMode {
+		services.ClearAuthCookies(c)
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// LogoutAll godoc
+// @Summary      Logout All
+// @Description  revokes every token of the user
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/logout-all [post]
+// @Security     ApiKeyAuth
+func LogoutAll(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	err := services.BlacklistUserTokens(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	if services.Config.CookieMode {
+		services.ClearAuthCookies(c)
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// getTokenDevice reads the client of a new session, device name is optionally sent by the client
+func getTokenDevice(c *gin.Context) *db.TokenDevice {
+	return db.NewTokenDevice(c.GetHeader("X-Device-Name"), c.Request.UserAgent(), c.ClientIP())
+}
+
+// getTokenResponse returns tokens for the response body,
+// in cookie mode tokens are set as cookies and only their expire dates are returned with the CSRF token
+func getTokenResponse(c *gin.Context, accessToken *db.Token, refreshToken *db.Token) (gin.H, error) {
+	access := accessToken.GetResponseJson()
+	refresh := refreshToken.GetResponseJson()
+	if !services.Config.CookieMode {
+		return gin.H{"access": access, "refresh": refresh}, nil
+	}
+
+	csrfToken, err := services.SetAuthCookies(c, accessToken, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(access, "token")
+	delete(refresh, "token")
+	return gin.H{"access": access, "refresh": refresh, "csrf": csrfToken}, nil
+}
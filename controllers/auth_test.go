@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogoutRequiresRefreshTokenOfSameSession(t *testing.T) {
+	useTestDatabase(t)
+
+	user := createTestUser(t, "logout@example.com", db.RoleUser)
+	access, refresh, err := services.GenerateAccessTokens(user, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherRefresh, err := services.GenerateAccessTokens(user, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.POST("/logout", middlewares.JWTMiddleware(), Logout)
+	logout := func(refreshToken string) int {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(`{"token":"`+refreshToken+`"}`))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer "+access.Token)
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	if code := logout(otherRefresh.Token); code != http.StatusBadRequest {
+		t.Fatalf("expected refresh token of another session to be rejected, got %d", code)
+	}
+	if _, err = services.VerifyToken(otherRefresh.Token, db.TokenTypeRefresh); err != nil {
+		t.Fatalf("expected other session to stay logged in, got %v", err)
+	}
+
+	if code := logout(refresh.Token); code != http.StatusOK {
+		t.Fatalf("expected logout with own refresh token, got %d", code)
+	}
+	if _, err = services.VerifyToken(refresh.Token, db.TokenTypeRefresh); err == nil {
+		t.Fatal("expected refresh token to be revoked")
+	}
+	if _, err = services.VerifyToken(access.Token, db.TokenTypeAccess); err == nil {
+		t.Fatal("expected access token to be revoked")
+	}
+}
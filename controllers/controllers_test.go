@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"sync"
+	"testing"
+)
+
+var testDatabaseOnce sync.Once
+var testDatabaseErr error
+
+// useTestDatabase connects to TEST_MONGO_URI and drops the test database, tests are skipped without it
+func useTestDatabase(t *testing.T) *models.EnvConfig {
+	t.Helper()
+
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+
+	previous := services.Config
+	services.Config = &models.EnvConfig{
+		MongodbDatabase:            "go_starter_test",
+		JWTSecretKey:               "test-secret",
+		JWTAlgorithm:               services.JWTAlgorithmHS256,
+		JWTAccessExpirationMinutes: 15,
+		JWTRefreshExpirationDays:   7,
+		Mode:                       "test",
+	}
+	t.Cleanup(func() {
+		services.Config = previous
+	})
+
+	testDatabaseOnce.Do(func() {
+		testDatabaseErr = mgm.SetDefaultConfig(nil, services.Config.MongodbDatabase, options.Client().ApplyURI(uri))
+	})
+	if testDatabaseErr != nil {
+		t.Fatal(testDatabaseErr)
+	}
+
+	_, _, database, _ := mgm.DefaultConfigs()
+	if err := database.Drop(mgm.Ctx()); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	return services.Config
+}
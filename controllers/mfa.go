@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// SetupTOTP godoc
+// @Summary      Setup TOTP
+// @Description  creates a TOTP secret, mfa is enabled after confirming a code
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/mfa/totp/setup [post]
+// @Security     ApiKeyAuth
+func SetupTOTP(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	user, err := services.FindUserById(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	uri, err := services.SetupTOTP(user)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"otpauth_uri": uri, "secret": user.MFASecret}
+	response.SendResponse(c)
+}
+
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP
+// @Description  enables mfa with a code from the authenticator app, returns recovery codes once
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.MFACodeRequest true "MFA Code Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/mfa/totp/confirm [post]
+// @Security     ApiKeyAuth
+func ConfirmTOTP(c *gin.Context) {
+	var requestBody models.MFACodeRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	user, err := services.FindUserById(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	recoveryCodes, err := services.ConfirmTOTP(user, requestBody.Code)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"recovery_codes": recoveryCodes}
+	response.SendResponse(c)
+}
+
+// VerifyMFA godoc
+// @Summary      Verify MFA
+// @Description  exchanges login mfa challenge and a TOTP or recovery code with access tokens
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.MFAVerifyRequest true "MFA Verify Request"
+// @Param        X-Device-Name  header  string  false  "Device name shown in sessions"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Router       /auth/mfa/verify [post]
+func VerifyMFA(c *gin.Context) {
+	var requestBody models.MFAVerifyRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	user, err := services.FindMFAChallengeUser(requestBody.Token)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	// user might be disabled after the challenge was created
+	if user.Disabled {
+		response.StatusCode = http.StatusForbidden
+		response.Message = "account is disabled"
+		response.SendResponse(c)
+		return
+	}
+
+	// wrong codes count as failed logins
+	wait, err := services.ReserveLoginAttempt(user.Email, c.ClientIP())
+	if err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		response.StatusCode = http.StatusTooManyRequests
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	err = services.CompleteMFAChallenge(user, requestBody.Token, requestBody.Code)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	services.ReleaseLoginAttempt(user.Email, c.ClientIP())
+
+	accessToken, refreshToken, err := services.GenerateAccessTokens(user, getTokenDevice(c))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	tokens, err := getTokenResponse(c, accessToken, refreshToken)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{
+		"user":  user,
+		"token": tokens,
+	}
+	response.SendResponse(c)
+}
+
+// DisableMFA godoc
+// @Summary      Disable MFA
+// @Description  disables mfa after password check, users without password send a TOTP or recovery code
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.MFADisableRequest true "MFA Disable Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /auth/mfa/disable [post]
+// @Security     ApiKeyAuth
+func DisableMFA(c *gin.Context) {
+	var requestBody models.MFADisableRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	user, err := services.FindUserById(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	if user.Password == "" {
+		// users created with OIDC login have no password to check
+		if err = services.CheckMFACode(user, requestBody.Code); err != nil {
+			response.Message = err.Error()
+			response.SendResponse(c)
+			return
+		}
+	} else if !services.CheckUserPassword(user, requestBody.Password) {
+		response.Message = "password is wrong"
+		response.SendResponse(c)
+		return
+	}
+
+	err = services.DisableMFA(user)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"user": user}
+	response.SendResponse(c)
+}
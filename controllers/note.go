@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // CreateNewNote godoc
@@ -37,7 +38,8 @@ func CreateNewNote(c *gin.Context) {
 		return
 	}
 
-	note, err := services.CreateNote(userId.(primitive.ObjectID), requestBody.Title, requestBody.Content)
+	notebookId := optionalObjectId(requestBody.NotebookId)
+	note, err := services.CreateNote(userId.(primitive.ObjectID), requestBody.Title, requestBody.Content, requestBody.Tags, notebookId)
 	if err != nil {
 		response.Message = err.Error()
 		response.SendResponse(c)
@@ -52,11 +54,13 @@ func CreateNewNote(c *gin.Context) {
 
 // GetNotes godoc
 // @Summary      Get Notes
-// @Description  gets user notes with pagination
+// @Description  gets user notes with pagination, filtered by tags
 // @Tags         notes
 // @Accept       json
 // @Produce      json
-// @Param        page  query    string  false  "Switch page by 'page'"
+// @Param        page   query    string    false  "Switch page by 'page'"
+// @Param        tag    query    []string  false  "Filter by tags, e.g. ?tag=a&tag=b"  collectionFormat(multi)
+// @Param        match  query    string    false  "Notes with 'any' (default) or 'all' of the tags"  Enums(any, all)
 // @Success      200  {object}  models.Response
 // @Failure      400  {object}  models.Response
 // @Router       /notes [get]
@@ -78,7 +82,60 @@ func GetNotes(c *gin.Context) {
 	page, _ := strconv.Atoi(pageQuery)
 	limit := 5
 
-	notes, _ := services.GetNotes(userId.(primitive.ObjectID), page, limit)
+	tags := c.QueryArray("tag")
+	matchAll := c.Query("match") == "all"
+
+	notes, _ := services.GetNotes(userId.(primitive.ObjectID), tags, matchAll, page, limit)
+	hasPrev := page > 0
+	hasNext := len(notes) > limit
+
+	if hasNext {
+		notes = notes[:limit]
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"notes": notes, "prev": hasPrev, "next": hasNext}
+	response.SendResponse(c)
+}
+
+// SearchNotes godoc
+// @Summary      Search Notes
+// @Description  searches user notes by title and content, sorted by relevance with highlighted snippets
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        q     query    string  true   "Search words, \"exact phrases\" and -excluded words"
+// @Param        page  query    string  false  "Switch page by 'page'"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notes/search [get]
+// @Security     ApiKeyAuth
+func SearchNotes(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	pageQuery := c.DefaultQuery("page", "0")
+	page, _ := strconv.Atoi(pageQuery)
+	limit := 5
+
+	notes, err := services.SearchNotes(userId.(primitive.ObjectID), query, page, limit)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
 	hasPrev := page > 0
 	hasNext := len(notes) > limit
 
@@ -150,6 +207,7 @@ func GetOneNote(c *gin.Context) {
 // @Param        req    body    models.NoteRequest true "Note Request"
 // @Success      200  {object}  models.Response
 // @Failure      400  {object}  models.Response
+// @Failure      409  {object}  models.Response
 // @Router       /notes/{id} [put]
 // @Security     ApiKeyAuth
 func UpdateNote(c *gin.Context) {
@@ -173,6 +231,9 @@ func UpdateNote(c *gin.Context) {
 
 	err := services.UpdateNote(userId.(primitive.ObjectID), noteId, &noteRequest)
 	if err != nil {
+		if err == services.ErrNoteConflict {
+			response.StatusCode = http.StatusConflict
+		}
 		response.Message = err.Error()
 		response.SendResponse(c)
 		return
@@ -185,7 +246,7 @@ func UpdateNote(c *gin.Context) {
 
 // DeleteNote godoc
 // @Summary      Delete a note
-// @Description  deletes note by id
+// @Description  moves note to trash by id
 // @Tags         notes
 // @Accept       json
 // @Produce      json
@@ -221,3 +282,209 @@ func DeleteNote(c *gin.Context) {
 	response.Success = true
 	response.SendResponse(c)
 }
+
+// MoveNote godoc
+// @Summary      Move a note
+// @Description  moves a note to a notebook, an empty notebook_id moves it out of notebooks
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        id     path    string  true  "Note ID"
+// @Param        req    body    models.MoveNoteRequest true "Move Note Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notes/{id}/notebook [put]
+// @Security     ApiKeyAuth
+func MoveNote(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	noteId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	var requestBody models.MoveNoteRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	err := services.MoveNote(userId.(primitive.ObjectID), noteId, optionalObjectId(requestBody.NotebookId))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// GetTrashedNotes godoc
+// @Summary      Get Trash
+// @Description  gets deleted notes in trash with pagination
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        page  query    string  false  "Switch page by 'page'"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notes/trash [get]
+// @Security     ApiKeyAuth
+func GetTrashedNotes(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	pageQuery := c.DefaultQuery("page", "0")
+	page, _ := strconv.Atoi(pageQuery)
+	limit := 5
+
+	notes, err := services.GetTrashedNotes(userId.(primitive.ObjectID), page, limit)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	hasPrev := page > 0
+	hasNext := len(notes) > limit
+
+	if hasNext {
+		notes = notes[:limit]
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"notes": notes, "prev": hasPrev, "next": hasNext}
+	response.SendResponse(c)
+}
+
+// RestoreNote godoc
+// @Summary      Restore a note
+// @Description  moves a note out of trash
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Note ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notes/{id}/restore [post]
+// @Security     ApiKeyAuth
+func RestoreNote(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	noteId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	note, err := services.RestoreNote(userId.(primitive.ObjectID), noteId)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"note": note}
+	response.SendResponse(c)
+}
+
+// DeleteTrashedNote godoc
+// @Summary      Delete a note permanently
+// @Description  deletes a note in trash permanently with its versions
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Note ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notes/trash/{id} [delete]
+// @Security     ApiKeyAuth
+func DeleteTrashedNote(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	noteId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	err := services.DeleteTrashedNote(userId.(primitive.ObjectID), noteId)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// EmptyTrash godoc
+// @Summary      Empty trash
+// @Description  deletes all notes in trash permanently
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notes/trash [delete]
+// @Security     ApiKeyAuth
+func EmptyTrash(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	deleted, err := services.EmptyTrash(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"deleted": deleted}
+	response.SendResponse(c)
+}
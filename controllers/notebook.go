@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CreateNotebook godoc
+// @Summary      Create Notebook
+// @Description  creates a notebook, it is nested under the notebook of parent_id
+// @Tags         notebooks
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.NotebookRequest true "Notebook Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notebooks [post]
+// @Security     ApiKeyAuth
+func CreateNotebook(c *gin.Context) {
+	var requestBody models.NotebookRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	name := strings.TrimSpace(requestBody.Name)
+	notebook, err := services.CreateNotebook(userId.(primitive.ObjectID), name, optionalObjectId(requestBody.ParentId))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusCreated
+	response.Success = true
+	response.Data = gin.H{"notebook": notebook}
+	response.SendResponse(c)
+}
+
+// GetNotebooks godoc
+// @Summary      Get Notebooks
+// @Description  gets all notebooks of the user, the tree can be built with parent_id
+// @Tags         notebooks
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notebooks [get]
+// @Security     ApiKeyAuth
+func GetNotebooks(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	notebooks, err := services.GetNotebooks(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"notebooks": notebooks}
+	response.SendResponse(c)
+}
+
+// GetOneNotebook godoc
+// @Summary      Get a notebook
+// @Description  get notebook by id
+// @Tags         notebooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Notebook ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notebooks/{id} [get]
+// @Security     ApiKeyAuth
+func GetOneNotebook(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	notebookId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	notebook, err := services.GetNotebookById(userId.(primitive.ObjectID), notebookId)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"notebook": notebook}
+	response.SendResponse(c)
+}
+
+// UpdateNotebook godoc
+// @Summary      Update a notebook
+// @Description  renames a notebook or moves it with its sub notebooks under another parent
+// @Tags         notebooks
+// @Accept       json
+// @Produce      json
+// @Param        id     path    string  true  "Notebook ID"
+// @Param        req    body    models.NotebookRequest true "Notebook Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notebooks/{id} [put]
+// @Security     ApiKeyAuth
+func UpdateNotebook(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	notebookId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	var requestBody models.NotebookRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	name := strings.TrimSpace(requestBody.Name)
+	notebook, err := services.UpdateNotebook(userId.(primitive.ObjectID), notebookId, name, optionalObjectId(requestBody.ParentId))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"notebook": notebook}
+	response.SendResponse(c)
+}
+
+// DeleteNotebook godoc
+// @Summary      Delete a notebook
+// @Description  deletes a notebook, its notes and sub notebooks are moved to its parent, with mode=cascade sub notebooks are deleted and notes are moved to trash
+// @Tags         notebooks
+// @Accept       json
+// @Produce      json
+// @Param        id    path     string  true   "Notebook ID"
+// @Param        mode  query    string  false  "'move' (default) or 'cascade'"  Enums(move, cascade)
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notebooks/{id} [delete]
+// @Security     ApiKeyAuth
+func DeleteNotebook(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	notebookId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	mode := c.DefaultQuery("mode", "move")
+	if mode != "move" && mode != "cascade" {
+		response.Message = "invalid mode: " + mode
+		response.SendResponse(c)
+		return
+	}
+
+	err := services.DeleteNotebook(userId.(primitive.ObjectID), notebookId, mode == "cascade")
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// GetNotebookNotes godoc
+// @Summary      Get Notebook Notes
+// @Description  gets notes of a notebook and its sub notebooks with pagination
+// @Tags         notebooks
+// @Accept       json
+// @Produce      json
+// @Param        id    path     string  true   "Notebook ID"
+// @Param        page  query    string  false  "Switch page by 'page'"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notebooks/{id}/notes [get]
+// @Security     ApiKeyAuth
+func GetNotebookNotes(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	notebookId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	pageQuery := c.DefaultQuery("page", "0")
+	page, _ := strconv.Atoi(pageQuery)
+	limit := 5
+
+	notes, err := services.GetNotebookNotes(userId.(primitive.ObjectID), notebookId, page, limit)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	hasPrev := page > 0
+	hasNext := len(notes) > limit
+
+	if hasNext {
+		notes = notes[:limit]
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"notes": notes, "prev": hasPrev, "next": hasNext}
+	response.SendResponse(c)
+}
+
+// optionalObjectId returns nil for empty ids, ids are validated before
+func optionalObjectId(idHex string) *primitive.ObjectID {
+	if idHex == "" {
+		return nil
+	}
+
+	id, _ := primitive.ObjectIDFromHex(idHex)
+	return &id
+}
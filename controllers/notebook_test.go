@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeleteNotebookRejectsUnknownMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userId", primitive.NewObjectID())
+	})
+	router.DELETE("/notebooks/:id", DeleteNotebook)
+
+	for _, mode := range []string{"casade", "CASCADE", "", "move,cascade"} {
+		t.Run(mode, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/notebooks/"+primitive.NewObjectID().Hex()+"?mode="+mode, nil)
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strconv"
+)
+
+// GetNoteVersions godoc
+// @Summary      Get Note Versions
+// @Description  gets previous versions of a note with pagination, newest versions are first
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        id    path     string  true   "Note ID"
+// @Param        page  query    string  false  "Switch page by 'page'"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notes/{id}/versions [get]
+// @Security     ApiKeyAuth
+func GetNoteVersions(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	noteId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	pageQuery := c.DefaultQuery("page", "0")
+	page, _ := strconv.Atoi(pageQuery)
+	limit := 5
+
+	versions, err := services.GetNoteVersions(userId.(primitive.ObjectID), noteId, page, limit)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	hasPrev := page > 0
+	hasNext := len(versions) > limit
+
+	if hasNext {
+		versions = versions[:limit]
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"versions": versions, "prev": hasPrev, "next": hasNext}
+	response.SendResponse(c)
+}
+
+// DiffNoteVersions godoc
+// @Summary      Diff Note Versions
+// @Description  gets line based unified diff of note content between two versions
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        id    path     string  true   "Note ID"
+// @Param        from  query    int     true   "Version to compare from"
+// @Param        to    query    int     false  "Version to compare to, current version by default"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /notes/{id}/versions/diff [get]
+// @Security     ApiKeyAuth
+func DiffNoteVersions(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	noteId, _ := primitive.ObjectIDFromHex(idHex)
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	from, _ := strconv.Atoi(c.Query("from"))
+	fromVersion, err := services.GetNoteVersion(userId.(primitive.ObjectID), noteId, from)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	note, err := services.GetNoteById(userId.(primitive.ObjectID), noteId)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	to := note.CurrentVersion()
+	if toQuery := c.Query("to"); toQuery != "" {
+		to, _ = strconv.Atoi(toQuery)
+	}
+
+	toVersion, err := services.GetNoteVersion(userId.(primitive.ObjectID), noteId, to)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	diff := services.UnifiedDiff(
+		"version "+strconv.Itoa(fromVersion.Version),
+		"version "+strconv.Itoa(toVersion.Version),
+		fromVersion.Content,
+		toVersion.Content,
+	)
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{
+		"from":  fromVersion.Version,
+		"to":    toVersion.Version,
+		"title": gin.H{"from": fromVersion.Title, "to": toVersion.Title},
+		"diff":  diff,
+	}
+	response.SendResponse(c)
+}
+
+// RestoreNoteVersion godoc
+// @Summary      Restore Note Version
+// @Description  restores title, content and tags of a note from a version, the current state is kept as a version
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        id       path    string  true  "Note ID"
+// @Param        version  path    int     true  "Version"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      409  {object}  models.Response
+// @Router       /notes/{id}/versions/{version}/restore [post]
+// @Security     ApiKeyAuth
+func RestoreNoteVersion(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	idHex := c.Param("id")
+	noteId, _ := primitive.ObjectIDFromHex(idHex)
+	version, _ := strconv.Atoi(c.Param("version"))
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	note, err := services.RestoreNoteVersion(userId.(primitive.ObjectID), noteId, version)
+	if err != nil {
+		if err == services.ErrNoteConflict {
+			response.StatusCode = http.StatusConflict
+		}
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"note": note}
+	response.SendResponse(c)
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"net/http"
+)
+
+// OIDCAuthorize godoc
+// @Summary      OIDC Authorize
+// @Description  starts login with an OIDC provider, client redirects the user to the returned authorization url.
+// @Description  Login is bound to the browser with an HttpOnly oidc_verifier cookie.
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        provider  path      string  true  "Provider name"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Router       /auth/oidc/{provider} [get]
+func OIDCAuthorize(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	provider, err := services.GetOIDCProvider(c.Param("provider"))
+	if err != nil {
+		response.StatusCode = http.StatusNotFound
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	authorizationUrl, codeVerifier, err := services.StartOIDCLogin(provider)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	services.SetOIDCVerifierCookie(c, codeVerifier)
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"authorization_url": authorizationUrl}
+	response.SendResponse(c)
+}
+
+// OIDCCallback godoc
+// @Summary      OIDC Callback
+// @Description  exchanges code and state which the provider redirected with, links the account by verified email.
+// @Description  It has to be sent with oidc_verifier cookie of the browser which started the login.
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        provider  path      string  true  "Provider name"
+// @Param        req  body      models.OIDCCallbackRequest true "OIDC Callback Request"
+// @Param        X-Device-Name  header  string  false  "Device name shown in sessions"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Router       /auth/oidc/{provider}/callback [post]
+func OIDCCallback(c *gin.Context) {
+	var requestBody models.OIDCCallbackRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	provider, err := services.GetOIDCProvider(c.Param("provider"))
+	if err != nil {
+		response.StatusCode = http.StatusNotFound
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	codeVerifier := services.PopOIDCVerifierCookie(c)
+	user, err := services.FinishOIDCLogin(provider, requestBody.Code, requestBody.State, codeVerifier)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	if user.Disabled {
+		response.StatusCode = http.StatusForbidden
+		response.Message = "account is disabled"
+		response.SendResponse(c)
+		return
+	}
+
+	// mfa is required like password login
+	if user.MFAEnabled {
+		challenge, err := services.CreateMFAChallenge(user)
+		if err != nil {
+			response.Message = err.Error()
+			response.SendResponse(c)
+			return
+		}
+
+		response.StatusCode = http.StatusOK
+		response.Success = true
+		response.Data = gin.H{"mfa_required": true, "mfa_token": challenge.GetResponseJson()}
+		response.SendResponse(c)
+		return
+	}
+
+	accessToken, refreshToken, err := services.GenerateAccessTokens(user, getTokenDevice(c))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	tokens, err := getTokenResponse(c, accessToken, refreshToken)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{
+		"user":  user,
+		"token": tokens,
+	}
+	response.SendResponse(c)
+}
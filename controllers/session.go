@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+)
+
+// GetSessions godoc
+// @Summary      Get Sessions
+// @Description  lists active sessions of the user with device info and last used time
+// @Tags         sessions
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /users/me/sessions [get]
+// @Security     ApiKeyAuth
+func GetSessions(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	sessions, err := services.GetActiveSessions(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	tokenFamily, _ := c.Get("tokenFamily")
+	family, _ := tokenFamily.(primitive.ObjectID)
+
+	sessionList := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		current := !family.IsZero() && session.Family == family
+		sessionList = append(sessionList, session.GetSessionJson(current))
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"sessions": sessionList}
+	response.SendResponse(c)
+}
+
+// RevokeSession godoc
+// @Summary      Revoke Session
+// @Description  revokes a session by id, the device has to login again
+// @Tags         sessions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Session ID"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /users/me/sessions/{id} [delete]
+// @Security     ApiKeyAuth
+func RevokeSession(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	sessionId, _ := primitive.ObjectIDFromHex(c.Param("id"))
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	err := services.RevokeSession(userId.(primitive.ObjectID), sessionId)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
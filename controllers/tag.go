@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+)
+
+// GetTags godoc
+// @Summary      Get Tags
+// @Description  gets tags of the user with their note counts
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /tags [get]
+// @Security     ApiKeyAuth
+func GetTags(c *gin.Context) {
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	tags, err := services.GetTags(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"tags": tags}
+	response.SendResponse(c)
+}
+
+// RenameTag godoc
+// @Summary      Rename a tag
+// @Description  renames a tag on all notes of the user, it is merged if the new name is already used
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        tag  path      string  true  "Tag"
+// @Param        req  body      models.RenameTagRequest true "Rename Tag Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /tags/{tag} [put]
+// @Security     ApiKeyAuth
+func RenameTag(c *gin.Context) {
+	var requestBody models.RenameTagRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	updated, err := services.RenameTag(userId.(primitive.ObjectID), c.Param("tag"), requestBody.Name)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"updated": updated}
+	response.SendResponse(c)
+}
+
+// MergeTags godoc
+// @Summary      Merge tags
+// @Description  replaces source tags with the target tag on all notes of the user
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.MergeTagsRequest true "Merge Tags Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /tags/merge [post]
+// @Security     ApiKeyAuth
+func MergeTags(c *gin.Context) {
+	var requestBody models.MergeTagsRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	updated, err := services.MergeTags(userId.(primitive.ObjectID), requestBody.Sources, requestBody.Target)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Data = gin.H{"updated": updated}
+	response.SendResponse(c)
+}
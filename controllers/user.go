@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+)
+
+// ChangePassword godoc
+// @Summary      Change Password
+// @Description  changes password of the current user and revokes other sessions
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.ChangePasswordRequest true "Change Password Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /users/me/password [put]
+// @Security     ApiKeyAuth
+func ChangePassword(c *gin.Context) {
+	var requestBody models.ChangePasswordRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	user, err := services.FindUserById(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	if !services.CheckUserPassword(user, requestBody.CurrentPassword) {
+		response.Message = "current password is wrong"
+		response.SendResponse(c)
+		return
+	}
+
+	err = services.UpdateUserPassword(user, requestBody.Password)
+	if err != nil {
+		models.SendValidationErrorResponse(c, err)
+		return
+	}
+
+	// keep the current session, revoke the others
+	tokenFamily, _ := c.Get("tokenFamily")
+	family, _ := tokenFamily.(primitive.ObjectID)
+	err = services.BlacklistOtherUserTokens(user.ID, family)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.SendResponse(c)
+}
+
+// ChangeEmail godoc
+// @Summary      Change Email
+// @Description  sends a confirmation link to the new email, email changes after confirmation
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        req  body      models.ChangeEmailRequest true "Change Email Request"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Router       /users/me/email [put]
+// @Security     ApiKeyAuth
+func ChangeEmail(c *gin.Context) {
+	var requestBody models.ChangeEmailRequest
+	_ = c.ShouldBindBodyWith(&requestBody, binding.JSON)
+
+	response := &models.Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+	}
+
+	userId, exists := c.Get("userId")
+	if !exists {
+		response.Message = "cannot get user"
+		response.SendResponse(c)
+		return
+	}
+
+	user, err := services.FindUserById(userId.(primitive.ObjectID))
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	if !services.CheckUserPassword(user, requestBody.Password) {
+		response.Message = "password is wrong"
+		response.SendResponse(c)
+		return
+	}
+
+	err = services.SendEmailChangeMail(user, requestBody.Email)
+	if err != nil {
+		response.Message = err.Error()
+		response.SendResponse(c)
+		return
+	}
+
+	response.StatusCode = http.StatusOK
+	response.Success = true
+	response.Message = "confirmation link has been sent to the new email"
+	response.SendResponse(c)
+}
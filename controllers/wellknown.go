@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  returns public signing keys to verify tokens without the shared secret, it is served outside of /v1 without models.Response
+// @Tags         well-known
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "JSON Web Key Set with a keys array"
+// @Router       /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, services.GetJWKS())
+}
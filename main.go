@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/routes"
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
 	"log"
@@ -29,15 +30,35 @@ import (
 
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
-// @name Bearer-Token
+// @name Authorization
+// @description Access token with "Bearer " prefix, e.g. "Bearer eyJhbGciOi..."
 func main() {
+	setRole := flag.String("set-role", "", "sets role of the user with -email and exits, e.g. -set-role admin")
+	email := flag.String("email", "", "user email for -set-role")
+	flag.Parse()
+
 	services.LoadConfig()
+	services.LoadSigningKeys()
 	services.InitMongoDB()
+	services.MigrateTokenHashes()
+	services.EnsureTokenIndexes()
+	services.EnsureApiKeyIndexes()
+	services.EnsureNoteIndexes()
+	services.EnsureNotebookIndexes()
+	services.EnsureNoteVersionIndexes()
+
+	if *setRole != "" {
+		runSetRole(*email, *setRole)
+		return
+	}
 
 	if services.Config.UseRedis {
 		services.CheckRedisConnection()
 	}
 
+	services.StartTrashPurgeJob()
+	services.StartNoteVersionPruneJob()
+
 	routes.InitGin()
 	router := routes.New()
 
@@ -57,7 +78,7 @@ func main() {
 
 	// Wait for interrupt signal to gracefully shut down the server with
 	// a timeout of 15 seconds.
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	log.Println("Shutdown Server ...")
@@ -69,3 +90,17 @@ func main() {
 	}
 	log.Println("Server exiting")
 }
+
+// runSetRole promotes or demotes a user from command line
+func runSetRole(email string, role string) {
+	user, err := services.FindUserByEmail(email)
+	if err != nil {
+		log.Fatal(err.Error() + ": " + email)
+	}
+
+	if err = services.SetUserRole(user, role); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Role of %s is set to %s\n", user.Email, user.Role)
+}
@@ -6,20 +6,141 @@ import (
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strings"
 )
 
+const authRealm = "api"
+
+// tokenErrorCodes are sent in error payload, so clients can tell whether to refresh or login again
+var tokenErrorCodes = map[error]string{
+	services.ErrTokenMissing:   "token_missing",
+	services.ErrTokenMalformed: "token_malformed",
+	services.ErrTokenScheme:    "token_invalid_scheme",
+	services.ErrTokenInvalid:   "token_invalid",
+	services.ErrTokenExpired:   "token_expired",
+	services.ErrTokenRevoked:   "token_revoked",
+}
+
 func JWTMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.GetHeader("Bearer-Token")
+		token, err := getBearerToken(c)
+		if err != nil {
+			sendTokenError(c, err)
+			return
+		}
+
 		tokenModel, err := services.VerifyToken(token, db.TokenTypeAccess)
 		if err != nil {
-			models.SendErrorResponse(c, http.StatusUnauthorized, err.Error())
+			sendTokenError(c, err)
 			return
 		}
 
 		c.Set("userIdHex", tokenModel.User.Hex())
 		c.Set("userId", tokenModel.User)
+		c.Set("tokenId", tokenModel.ID)
+		c.Set("tokenFamily", tokenModel.Family)
+
+		go services.TouchSession(tokenModel)
+
+		// tokens issued before roles were added to claims
+		role := tokenModel.Claims.Role
+		if role == "" {
+			role = db.RoleUser
+		}
+		c.Set("userRole", role)
+
+		c.Next()
+	}
+}
+
+// AuthMiddleware accepts "Authorization: ApiKey <key>" header of machine clients,
+// other requests are authenticated by JWTMiddleware
+func AuthMiddleware() gin.HandlerFunc {
+	jwtMiddleware := JWTMiddleware()
+
+	return func(c *gin.Context) {
+		key, found := strings.CutPrefix(c.GetHeader("Authorization"), "ApiKey ")
+		if !found {
+			jwtMiddleware(c)
+			return
+		}
+
+		apiKey, err := services.VerifyApiKey(strings.TrimSpace(key))
+		if err != nil {
+			c.Header("WWW-Authenticate", `ApiKey realm="`+authRealm+`"`)
+			models.SendErrorResponse(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		// role is read from db, api keys live longer than access tokens
+		user, err := services.FindUserById(apiKey.User)
+		if err != nil || user.Disabled {
+			c.Header("WWW-Authenticate", `ApiKey realm="`+authRealm+`"`)
+			models.SendErrorResponse(c, http.StatusUnauthorized, "not valid api key")
+			return
+		}
+
+		c.Set("userIdHex", user.ID.Hex())
+		c.Set("userId", user.ID)
+		c.Set("userRole", user.Role)
+		c.Set("apiKeyId", apiKey.ID)
+		c.Set("apiKeyScopes", apiKey.Scopes)
 
 		c.Next()
 	}
 }
+
+// getBearerToken reads "Authorization: Bearer <token>" header, other schemes are rejected.
+// Deprecated "Bearer-Token" header is read only if JWT_LEGACY_HEADER is enabled,
+// access token cookie is read only in cookie mode
+func getBearerToken(c *gin.Context) (string, error) {
+	if authorization := strings.TrimSpace(c.GetHeader("Authorization")); authorization != "" {
+		scheme, token, found := strings.Cut(authorization, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return "", services.ErrTokenScheme
+		}
+
+		token = strings.TrimSpace(token)
+		if !found || token == "" || strings.Contains(token, " ") {
+			return "", services.ErrTokenMalformed
+		}
+		return token, nil
+	}
+
+	if services.Config.JWTLegacyHeader {
+		if token := strings.TrimSpace(c.GetHeader("Bearer-Token")); token != "" {
+			return token, nil
+		}
+	}
+
+	// browser clients send the token with cookie in cookie mode, CSRFMiddleware checks these requests
+	if token := services.GetTokenCookie(c, services.AccessTokenCookie); token != "" {
+		return token, nil
+	}
+
+	return "", services.ErrTokenMissing
+}
+
+// sendTokenError sends 401 with RFC 6750 WWW-Authenticate challenge
+func sendTokenError(c *gin.Context, err error) {
+	code, ok := tokenErrorCodes[err]
+	if !ok {
+		code = tokenErrorCodes[services.ErrTokenInvalid]
+	}
+
+	challenge := `Bearer realm="` + authRealm + `"`
+	if err == services.ErrTokenScheme {
+		challenge += `, error="invalid_request", error_description="` + err.Error() + `"`
+	} else if err != services.ErrTokenMissing {
+		challenge += `, error="invalid_token", error_description="` + err.Error() + `"`
+	}
+	c.Header("WWW-Authenticate", challenge)
+
+	response := &models.Response{
+		StatusCode: http.StatusUnauthorized,
+		Success:    false,
+		Message:    err.Error(),
+		Data:       gin.H{"code": code},
+	}
+	response.SendResponse(c)
+}
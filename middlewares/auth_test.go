@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetBearerToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		cookie       string
+		cookieMode   bool
+		legacyHeader bool
+		token        string
+		err          error
+	}{
+		{name: "bearer", headers: map[string]string{"Authorization": "Bearer abc"}, token: "abc"},
+		{name: "lowercase scheme", headers: map[string]string{"Authorization": "bearer abc"}, token: "abc"},
+		{name: "missing", err: services.ErrTokenMissing},
+		{name: "empty bearer", headers: map[string]string{"Authorization": "Bearer "}, err: services.ErrTokenMalformed},
+		{name: "bearer with spaces", headers: map[string]string{"Authorization": "Bearer a b"}, err: services.ErrTokenMalformed},
+		{name: "api key scheme", headers: map[string]string{"Authorization": "ApiKey ak_abc"}, err: services.ErrTokenScheme},
+		{name: "basic scheme", headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, err: services.ErrTokenScheme},
+		{
+			name:       "other scheme does not fall back to cookie",
+			headers:    map[string]string{"Authorization": "ApiKey ak_abc"},
+			cookie:     "cookie-token",
+			cookieMode: true,
+			err:        services.ErrTokenScheme,
+		},
+		{name: "cookie", cookie: "cookie-token", cookieMode: true, token: "cookie-token"},
+		{name: "cookie without cookie mode", cookie: "cookie-token", err: services.ErrTokenMissing},
+		{name: "legacy header disabled", headers: map[string]string{"Bearer-Token": "abc"}, err: services.ErrTokenMissing},
+		{name: "legacy header enabled", headers: map[string]string{"Bearer-Token": "abc"}, legacyHeader: true, token: "abc"},
+	}
+
+	previous := services.Config
+	defer func() {
+		services.Config = previous
+	}()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			services.Config = &models.EnvConfig{CookieMode: test.cookieMode, JWTLegacyHeader: test.legacyHeader}
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range test.headers {
+				request.Header.Set(name, value)
+			}
+			if test.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: services.AccessTokenCookie, Value: test.cookie})
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = request
+
+			token, err := getBearerToken(c)
+			if err != test.err || token != test.token {
+				t.Fatalf("expected %q, %v; got %q, %v", test.token, test.err, token, err)
+			}
+		})
+	}
+}
+
+func TestSendTokenErrorInvalidScheme(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+
+	sendTokenError(c, services.ErrTokenScheme)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", recorder.Code)
+	}
+	if challenge := recorder.Header().Get("WWW-Authenticate"); !strings.Contains(challenge, `error="invalid_request"`) {
+		t.Fatalf("unexpected challenge %q", challenge)
+	}
+	if !strings.Contains(recorder.Body.String(), `"token_invalid_scheme"`) {
+		t.Fatalf("expected token_invalid_scheme code, got %s", recorder.Body.String())
+	}
+}
@@ -1,15 +1,41 @@
 package middlewares
 
 import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
 	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
 )
 
 func CORSMiddleware() gin.HandlerFunc {
+	allowedOrigins := map[string]bool{}
+	for _, origin := range strings.Split(services.Config.CORSAllowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			allowedOrigins[origin] = true
+		}
+	}
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		// credentials cannot be used with "*", only allowed origins are reflected.
+		// Cookie mode and OIDC login need credentials, other requests of any origin use bearer tokens
+		if len(allowedOrigins) > 0 {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if origin := c.GetHeader("Origin"); allowedOrigins[origin] {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		} else if !services.Config.CookieMode {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, X-Device-Name, Authorization, Bearer-Token, accept, origin, Cache-Control, X-Requested-With")
+
+		// preflight requests have no route
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
 
 		c.Next()
 	}
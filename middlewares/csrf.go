@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// CSRFMiddleware checks double submitted CSRF token of state changing requests which carry token cookies.
+// Requests with Authorization header are not authenticated by cookies, so they are not checked.
+func CSRFMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !services.Config.CookieMode || isSafeMethod(c.Request.Method) || c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+
+		accessToken := services.GetTokenCookie(c, services.AccessTokenCookie)
+		refreshToken := services.GetTokenCookie(c, services.RefreshTokenCookie)
+		if accessToken == "" && refreshToken == "" {
+			c.Next()
+			return
+		}
+
+		csrfCookie := services.GetTokenCookie(c, services.CSRFTokenCookie)
+		csrfHeader := c.GetHeader(services.CSRFTokenHeader)
+		if csrfCookie == "" || subtle.ConstantTimeCompare([]byte(csrfCookie), []byte(csrfHeader)) != 1 {
+			models.SendErrorResponse(c, http.StatusForbidden, "not valid csrf token")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
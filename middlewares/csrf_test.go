@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		cookieMode    bool
+		tokenCookie   string
+		csrfCookie    string
+		csrfHeader    string
+		authorization string
+		status        int
+	}{
+		{name: "matching header", method: http.MethodPost, cookieMode: true, tokenCookie: services.AccessTokenCookie, csrfCookie: "csrf", csrfHeader: "csrf", status: http.StatusOK},
+		{name: "header does not match cookie", method: http.MethodPost, cookieMode: true, tokenCookie: services.AccessTokenCookie, csrfCookie: "csrf", csrfHeader: "other", status: http.StatusForbidden},
+		{name: "missing header", method: http.MethodDelete, cookieMode: true, tokenCookie: services.AccessTokenCookie, csrfCookie: "csrf", status: http.StatusForbidden},
+		{name: "missing cookie", method: http.MethodPost, cookieMode: true, tokenCookie: services.RefreshTokenCookie, csrfHeader: "csrf", status: http.StatusForbidden},
+		{name: "empty header and cookie", method: http.MethodPost, cookieMode: true, tokenCookie: services.AccessTokenCookie, status: http.StatusForbidden},
+		{name: "safe method", method: http.MethodGet, cookieMode: true, tokenCookie: services.AccessTokenCookie, csrfCookie: "csrf", status: http.StatusOK},
+		{name: "authorization header", method: http.MethodPost, cookieMode: true, tokenCookie: services.AccessTokenCookie, authorization: "Bearer abc", status: http.StatusOK},
+		{name: "without token cookies", method: http.MethodPost, cookieMode: true, status: http.StatusOK},
+		{name: "cookie mode disabled", method: http.MethodPost, tokenCookie: services.AccessTokenCookie, csrfCookie: "csrf", csrfHeader: "other", status: http.StatusOK},
+	}
+
+	previous := services.Config
+	defer func() {
+		services.Config = previous
+	}()
+
+	gin.SetMode(gin.TestMode)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			services.Config = &models.EnvConfig{CookieMode: test.cookieMode}
+
+			router := gin.New()
+			router.Use(CSRFMiddleware())
+			router.Handle(test.method, "/", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(test.method, "/", nil)
+			if test.tokenCookie != "" {
+				request.AddCookie(&http.Cookie{Name: test.tokenCookie, Value: "token"})
+			}
+			if test.csrfCookie != "" {
+				request.AddCookie(&http.Cookie{Name: services.CSRFTokenCookie, Value: test.csrfCookie})
+			}
+			if test.csrfHeader != "" {
+				request.Header.Set(services.CSRFTokenHeader, test.csrfHeader)
+			}
+			if test.authorization != "" {
+				request.Header.Set("Authorization", test.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Fatalf("expected %d, got %d: %s", test.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// RequirePermission allows the request only if user's role has all permissions,
+// must be used after JWTMiddleware or AuthMiddleware
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("userRole")
+		scopes, isApiKey := c.Get("apiKeyScopes")
+
+		for _, permission := range permissions {
+			if !db.RoleHasPermission(role, permission) {
+				models.SendErrorResponse(c, http.StatusForbidden, "you don't have permission: "+permission)
+				return
+			}
+
+			// api keys are also limited with their scopes
+			if isApiKey && !hasScope(scopes.([]string), permission) {
+				models.SendErrorResponse(c, http.StatusForbidden, "api key doesn't have scope: "+permission)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func hasScope(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
+	}
+
+	return false
+}
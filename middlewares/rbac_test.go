@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   string
+		scopes []string
+		status int
+	}{
+		{name: "role has permission", role: db.RoleModerator, status: http.StatusOK},
+		{name: "role without permission", role: db.RoleUser, status: http.StatusForbidden},
+		{name: "missing role", status: http.StatusForbidden},
+		{name: "unknown role", role: "owner", status: http.StatusForbidden},
+		{name: "api key with scope", role: db.RoleModerator, scopes: []string{db.PermissionNotesRead, db.PermissionUsersRead}, status: http.StatusOK},
+		{name: "api key without scope", role: db.RoleModerator, scopes: []string{db.PermissionNotesRead}, status: http.StatusForbidden},
+		{name: "api key without scopes", role: db.RoleAdmin, scopes: []string{}, status: http.StatusForbidden},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if test.role != "" {
+					c.Set("userRole", test.role)
+				}
+				if test.scopes != nil {
+					c.Set("apiKeyScopes", test.scopes)
+				}
+			})
+			router.GET("/", RequirePermission(db.PermissionUsersRead), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if recorder.Code != test.status {
+				t.Fatalf("expected %d, got %d: %s", test.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
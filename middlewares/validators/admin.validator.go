@@ -0,0 +1,39 @@
+package validators
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"net/http"
+)
+
+func GetUsersValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		page := c.DefaultQuery("page", "0")
+		err := validation.Validate(page, is.Int)
+		if err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, "invalid page: "+page)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func SetRoleValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var setRoleRequest models.SetRoleRequest
+		_ = c.ShouldBindBodyWith(&setRoleRequest, binding.JSON)
+
+		if err := setRoleRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
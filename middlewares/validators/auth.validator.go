@@ -2,6 +2,7 @@ package validators
 
 import (
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"net/http"
@@ -14,7 +15,7 @@ func RegisterValidator() gin.HandlerFunc {
 		_ = c.ShouldBindBodyWith(&registerRequest, binding.JSON)
 
 		if err := registerRequest.Validate(); err != nil {
-			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			models.SendValidationErrorResponse(c, err)
 			return
 		}
 
@@ -43,6 +44,11 @@ func RefreshValidator() gin.HandlerFunc {
 		var refreshRequest models.RefreshRequest
 		_ = c.ShouldBindBodyWith(&refreshRequest, binding.JSON)
 
+		// refresh token is read from cookie in cookie mode
+		if refreshRequest.Token == "" {
+			refreshRequest.Token = services.GetTokenCookie(c, services.RefreshTokenCookie)
+		}
+
 		if err := refreshRequest.Validate(); err != nil {
 			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
@@ -51,3 +57,173 @@ func RefreshValidator() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func LogoutValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var logoutRequest models.LogoutRequest
+		_ = c.ShouldBindBodyWith(&logoutRequest, binding.JSON)
+
+		// refresh token is read from cookie in cookie mode
+		if logoutRequest.Token == "" {
+			logoutRequest.Token = services.GetTokenCookie(c, services.RefreshTokenCookie)
+		}
+
+		if err := logoutRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func VerifyEmailValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var verifyEmailRequest models.VerifyEmailRequest
+		_ = c.ShouldBindBodyWith(&verifyEmailRequest, binding.JSON)
+
+		if err := verifyEmailRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func OIDCCallbackValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var oidcCallbackRequest models.OIDCCallbackRequest
+		_ = c.ShouldBindBodyWith(&oidcCallbackRequest, binding.JSON)
+
+		if err := oidcCallbackRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func MagicLinkValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var magicLinkRequest models.MagicLinkRequest
+		_ = c.ShouldBindBodyWith(&magicLinkRequest, binding.JSON)
+
+		if err := magicLinkRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func MagicLinkVerifyValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var magicLinkVerifyRequest models.MagicLinkVerifyRequest
+		_ = c.ShouldBindBodyWith(&magicLinkVerifyRequest, binding.JSON)
+
+		if err := magicLinkVerifyRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ForgotPasswordValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var forgotPasswordRequest models.ForgotPasswordRequest
+		_ = c.ShouldBindBodyWith(&forgotPasswordRequest, binding.JSON)
+
+		if err := forgotPasswordRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ResetPasswordValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var resetPasswordRequest models.ResetPasswordRequest
+		_ = c.ShouldBindBodyWith(&resetPasswordRequest, binding.JSON)
+
+		if err := resetPasswordRequest.Validate(); err != nil {
+			models.SendValidationErrorResponse(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ConfirmEmailChangeValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var confirmEmailChangeRequest models.ConfirmEmailChangeRequest
+		_ = c.ShouldBindBodyWith(&confirmEmailChangeRequest, binding.JSON)
+
+		if err := confirmEmailChangeRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func MFACodeValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var mfaCodeRequest models.MFACodeRequest
+		_ = c.ShouldBindBodyWith(&mfaCodeRequest, binding.JSON)
+
+		if err := mfaCodeRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func MFAVerifyValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var mfaVerifyRequest models.MFAVerifyRequest
+		_ = c.ShouldBindBodyWith(&mfaVerifyRequest, binding.JSON)
+
+		if err := mfaVerifyRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func MFADisableValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var mfaDisableRequest models.MFADisableRequest
+		_ = c.ShouldBindBodyWith(&mfaDisableRequest, binding.JSON)
+
+		if err := mfaDisableRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
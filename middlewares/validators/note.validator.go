@@ -1,12 +1,15 @@
 package validators
 
 import (
+	"errors"
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 func CreateNoteValidator() gin.HandlerFunc {
@@ -34,6 +37,36 @@ func GetNotesValidator() gin.HandlerFunc {
 			return
 		}
 
+		filterRequest := models.NoteFilterRequest{
+			Tags:  c.QueryArray("tag"),
+			Match: c.Query("match"),
+		}
+		if err = filterRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func SearchNotesValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		query := strings.TrimSpace(c.Query("q"))
+		err := validation.Validate(query, validation.Required, validation.RuneLength(1, 256))
+		if err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, "invalid q: "+err.Error())
+			return
+		}
+
+		page := c.DefaultQuery("page", "0")
+		err = validation.Validate(page, is.Int)
+		if err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, "invalid page: "+page)
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -52,3 +85,62 @@ func UpdateNoteValidator() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func MoveNoteValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var moveNoteRequest models.MoveNoteRequest
+		_ = c.ShouldBindBodyWith(&moveNoteRequest, binding.JSON)
+
+		if err := moveNoteRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func PathVersionValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		version := c.Param("version")
+		err := validation.Validate(version, validation.Required, is.Int, validation.By(positiveNumber))
+		if err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, "invalid version: "+version)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func NoteVersionDiffValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		from := c.Query("from")
+		err := validation.Validate(from, validation.Required, is.Int, validation.By(positiveNumber))
+		if err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, "invalid from: "+from)
+			return
+		}
+
+		to := c.Query("to")
+		err = validation.Validate(to, is.Int, validation.By(positiveNumber))
+		if err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, "invalid to: "+to)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func positiveNumber(value interface{}) error {
+	number, _ := strconv.Atoi(value.(string))
+	if value.(string) != "" && number < 1 {
+		return errors.New("must be greater than 0")
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package validators
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"net/http"
+)
+
+func NotebookValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var notebookRequest models.NotebookRequest
+		_ = c.ShouldBindBodyWith(&notebookRequest, binding.JSON)
+
+		if err := notebookRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func DeleteNotebookValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		mode := c.DefaultQuery("mode", "move")
+		err := validation.Validate(mode, validation.Required, validation.In("move", "cascade"))
+		if err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, "invalid mode: "+mode)
+			return
+		}
+
+		c.Next()
+	}
+}
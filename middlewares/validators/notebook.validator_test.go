@@ -0,0 +1,36 @@
+package validators
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeleteNotebookValidator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/notebooks", DeleteNotebookValidator(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := map[string]int{
+		"":              http.StatusOK,
+		"?mode=move":    http.StatusOK,
+		"?mode=cascade": http.StatusOK,
+		"?mode=casade":  http.StatusBadRequest,
+		"?mode=":        http.StatusBadRequest,
+		"?mode=Cascade": http.StatusBadRequest,
+	}
+
+	for query, status := range tests {
+		t.Run(query, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/notebooks"+query, nil))
+
+			if recorder.Code != status {
+				t.Fatalf("expected %d, got %d", status, recorder.Code)
+			}
+		})
+	}
+}
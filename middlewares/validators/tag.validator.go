@@ -0,0 +1,39 @@
+package validators
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"net/http"
+)
+
+func RenameTagValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var renameTagRequest models.RenameTagRequest
+		_ = c.ShouldBindBodyWith(&renameTagRequest, binding.JSON)
+		renameTagRequest.Tag = c.Param("tag")
+
+		if err := renameTagRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func MergeTagsValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var mergeTagsRequest models.MergeTagsRequest
+		_ = c.ShouldBindBodyWith(&mergeTagsRequest, binding.JSON)
+
+		if err := mergeTagsRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package validators
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"net/http"
+)
+
+func ChangePasswordValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var changePasswordRequest models.ChangePasswordRequest
+		_ = c.ShouldBindBodyWith(&changePasswordRequest, binding.JSON)
+
+		if err := changePasswordRequest.Validate(); err != nil {
+			models.SendValidationErrorResponse(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ChangeEmailValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var changeEmailRequest models.ChangeEmailRequest
+		_ = c.ShouldBindBodyWith(&changeEmailRequest, binding.JSON)
+
+		if err := changeEmailRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func CreateApiKeyValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var createApiKeyRequest models.CreateApiKeyRequest
+		_ = c.ShouldBindBodyWith(&createApiKeyRequest, binding.JSON)
+
+		if err := createApiKeyRequest.Validate(); err != nil {
+			models.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
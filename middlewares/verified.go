@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+)
+
+// VerifiedMailMiddleware blocks users without a verified email when REQUIRE_VERIFIED_EMAIL is set,
+// must be used after JWTMiddleware
+func VerifiedMailMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !services.Config.RequireVerifiedEmail {
+			c.Next()
+			return
+		}
+
+		userId, exists := c.Get("userId")
+		if !exists {
+			models.SendErrorResponse(c, http.StatusUnauthorized, "cannot get user")
+			return
+		}
+
+		user, err := services.FindUserById(userId.(primitive.ObjectID))
+		if err != nil {
+			models.SendErrorResponse(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if !user.MailVerified {
+			models.SendErrorResponse(c, http.StatusForbidden, "email is not verified")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -6,19 +6,99 @@ import (
 )
 
 type EnvConfig struct {
-	ServerPort                 string `mapstructure:"SERVER_PORT"`
-	ServerAddr                 string `mapstructure:"SERVER_ADDR"`
-	MongodbUri                 string `mapstructure:"MONGO_URI"`
-	MongodbDatabase            string `mapstructure:"MONGO_DATABASE"`
-	UseRedis                   bool   `mapstructure:"USE_REDIS"`
-	RedisDefaultAddr           string `mapstructure:"REDIS_DEFAULT_ADDR"`
-	JWTSecretKey               string `mapstructure:"JWT_SECRET"`
-	JWTAccessExpirationMinutes int    `mapstructure:"JWT_ACCESS_EXPIRATION_MINUTES"`
-	JWTRefreshExpirationDays   int    `mapstructure:"JWT_REFRESH_EXPIRATION_DAYS"`
-	Mode                       string `mapstructure:"MODE"`
+	ServerPort                     string `mapstructure:"SERVER_PORT"`
+	ServerAddr                     string `mapstructure:"SERVER_ADDR"`
+	MongodbUri                     string `mapstructure:"MONGO_URI"`
+	MongodbDatabase                string `mapstructure:"MONGO_DATABASE"`
+	UseRedis                       bool   `mapstructure:"USE_REDIS"`
+	RedisDefaultAddr               string `mapstructure:"REDIS_DEFAULT_ADDR"`
+	JWTSecretKey                   string `mapstructure:"JWT_SECRET"`
+	JWTAlgorithm                   string `mapstructure:"JWT_ALGORITHM"`
+	JWTKeysPath                    string `mapstructure:"JWT_KEYS_PATH"`
+	JWTKeyId                       string `mapstructure:"JWT_KEY_ID"`
+	JWTAccessExpirationMinutes     int    `mapstructure:"JWT_ACCESS_EXPIRATION_MINUTES"`
+	JWTRefreshExpirationDays       int    `mapstructure:"JWT_REFRESH_EXPIRATION_DAYS"`
+	JWTLegacyHeader                bool   `mapstructure:"JWT_LEGACY_HEADER"`
+	JWTLegacyHS256                 bool   `mapstructure:"JWT_LEGACY_HS256"`
+	CookieMode                     bool   `mapstructure:"COOKIE_MODE"`
+	CookieDomain                   string `mapstructure:"COOKIE_DOMAIN"`
+	CookieSecure                   bool   `mapstructure:"COOKIE_SECURE"`
+	CookieSameSite                 string `mapstructure:"COOKIE_SAMESITE"`
+	CORSAllowedOrigins             string `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	AppUrl                         string `mapstructure:"APP_URL"`
+	MailDriver                     string `mapstructure:"MAIL_DRIVER"`
+	MailFrom                       string `mapstructure:"MAIL_FROM"`
+	MailLogPath                    string `mapstructure:"MAIL_LOG_PATH"`
+	SMTPHost                       string `mapstructure:"SMTP_HOST"`
+	SMTPPort                       string `mapstructure:"SMTP_PORT"`
+	SMTPUsername                   string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword                   string `mapstructure:"SMTP_PASSWORD"`
+	VerifyEmailExpirationHours     int    `mapstructure:"VERIFY_EMAIL_EXPIRATION_HOURS"`
+	VerifyEmailResendSeconds       int    `mapstructure:"VERIFY_EMAIL_RESEND_SECONDS"`
+	ResetPasswordExpirationMinutes int    `mapstructure:"RESET_PASSWORD_EXPIRATION_MINUTES"`
+	MagicLinkExpirationMinutes     int    `mapstructure:"MAGIC_LINK_EXPIRATION_MINUTES"`
+	LoginMaxAttempts               int    `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginMaxIpAttempts             int    `mapstructure:"LOGIN_MAX_IP_ATTEMPTS"`
+	LoginLockoutMinutes            int    `mapstructure:"LOGIN_LOCKOUT_MINUTES"`
+	MFAIssuer                      string `mapstructure:"MFA_ISSUER"`
+	PasswordMinLength              int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength              int    `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordMinEntropy             int    `mapstructure:"PASSWORD_MIN_ENTROPY"`
+	PasswordBlocklistPath          string `mapstructure:"PASSWORD_BLOCKLIST_PATH"`
+	PasswordHasher                 string `mapstructure:"PASSWORD_HASHER"`
+	Argon2MemoryKiB                int    `mapstructure:"ARGON2_MEMORY_KIB"`
+	Argon2Iterations               int    `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism              int    `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost                     int    `mapstructure:"BCRYPT_COST"`
+	OIDCProviders                  string `mapstructure:"OIDC_PROVIDERS"`
+	NotebookMaxDepth               int    `mapstructure:"NOTEBOOK_MAX_DEPTH"`
+	NoteVersionMaxCount            int    `mapstructure:"NOTE_VERSION_MAX_COUNT"`
+	NoteVersionMaxAgeDays          int    `mapstructure:"NOTE_VERSION_MAX_AGE_DAYS"`
+	TrashRetentionDays             int    `mapstructure:"TRASH_RETENTION_DAYS"`
+	RequireVerifiedEmail           bool   `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	Mode                           string `mapstructure:"MODE"`
+
+	// OIDC is read from OIDC_<NAME>_* variables of every provider in OIDC_PROVIDERS
+	OIDC map[string]OIDCProviderConfig `mapstructure:"-"`
+}
+
+type OIDCProviderConfig struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       string
+}
+
+func (config OIDCProviderConfig) Validate() error {
+	return validation.ValidateStruct(&config,
+		validation.Field(&config.Issuer, validation.Required, is.URL),
+		validation.Field(&config.ClientId, validation.Required),
+		validation.Field(&config.RedirectUrl, validation.Required, is.URL),
+	)
 }
 
 func (config *EnvConfig) Validate() error {
+	// shared secret is only required for symmetric signing
+	var jwtSecretRules []validation.Rule
+	var jwtKeyRules []validation.Rule
+	if config.JWTAlgorithm == "HS256" {
+		jwtSecretRules = append(jwtSecretRules, validation.Required)
+	} else {
+		jwtKeyRules = append(jwtKeyRules, validation.Required)
+	}
+
+	// browsers reject SameSite=None cookies without Secure
+	var cookieSecureRules []validation.Rule
+	if config.CookieMode && config.CookieSameSite == "none" {
+		cookieSecureRules = append(cookieSecureRules, validation.Required.Error("must be true with none same site"))
+	}
+
+	var smtpRules []validation.Rule
+	if config.MailDriver == "smtp" {
+		smtpRules = append(smtpRules, validation.Required)
+	}
+
 	return validation.ValidateStruct(config,
 		validation.Field(&config.ServerPort, is.Port),
 		validation.Field(&config.ServerAddr, validation.Required),
@@ -29,9 +109,46 @@ func (config *EnvConfig) Validate() error {
 		validation.Field(&config.UseRedis, validation.In(true, false)),
 		validation.Field(&config.RedisDefaultAddr),
 
-		validation.Field(&config.JWTSecretKey, validation.Required),
+		validation.Field(&config.JWTSecretKey, jwtSecretRules...),
+		validation.Field(&config.JWTAlgorithm, validation.In("HS256", "RS256", "ES256", "EdDSA")),
+		validation.Field(&config.JWTKeysPath, jwtKeyRules...),
+		validation.Field(&config.JWTKeyId, jwtKeyRules...),
 		validation.Field(&config.JWTAccessExpirationMinutes, validation.Required),
 		validation.Field(&config.JWTRefreshExpirationDays, validation.Required),
+		validation.Field(&config.JWTLegacyHeader, validation.In(true, false)),
+		validation.Field(&config.JWTLegacyHS256, validation.In(true, false)),
+
+		validation.Field(&config.CookieMode, validation.In(true, false)),
+		validation.Field(&config.CookieSecure, cookieSecureRules...),
+		validation.Field(&config.CookieSameSite, validation.In("lax", "strict", "none")),
+
+		validation.Field(&config.AppUrl, validation.Required, is.URL),
+		validation.Field(&config.MailDriver, validation.In("smtp", "log")),
+		validation.Field(&config.MailFrom, validation.Required, is.Email),
+		validation.Field(&config.SMTPHost, smtpRules...),
+		validation.Field(&config.SMTPPort, append(smtpRules, is.Port)...),
+		validation.Field(&config.VerifyEmailExpirationHours, validation.Required),
+		validation.Field(&config.VerifyEmailResendSeconds, validation.Min(0)),
+		validation.Field(&config.ResetPasswordExpirationMinutes, validation.Required),
+		validation.Field(&config.MagicLinkExpirationMinutes, validation.Required),
+		validation.Field(&config.LoginMaxAttempts, validation.Required),
+		validation.Field(&config.LoginMaxIpAttempts, validation.Required),
+		validation.Field(&config.LoginLockoutMinutes, validation.Required),
+		validation.Field(&config.MFAIssuer, validation.Required),
+		validation.Field(&config.PasswordMinLength, validation.Required, validation.Min(6)),
+		validation.Field(&config.PasswordMaxLength, validation.Required, validation.Min(config.PasswordMinLength)),
+		validation.Field(&config.PasswordMinEntropy, validation.Min(0)),
+		validation.Field(&config.PasswordHasher, validation.In("argon2id", "bcrypt")),
+		validation.Field(&config.Argon2MemoryKiB, validation.Required, validation.Min(8*1024)),
+		validation.Field(&config.Argon2Iterations, validation.Required, validation.Min(1)),
+		validation.Field(&config.Argon2Parallelism, validation.Required, validation.Min(1), validation.Max(255)),
+		validation.Field(&config.BcryptCost, validation.Required, validation.Min(10), validation.Max(31)),
+		validation.Field(&config.NotebookMaxDepth, validation.Required, validation.Min(1), validation.Max(20)),
+		validation.Field(&config.NoteVersionMaxCount, validation.Min(0)),
+		validation.Field(&config.NoteVersionMaxAgeDays, validation.Min(0)),
+		validation.Field(&config.TrashRetentionDays, validation.Min(0)),
+		validation.Field(&config.OIDC),
+		validation.Field(&config.RequireVerifiedEmail, validation.In(true, false)),
 
 		validation.Field(&config.Mode, validation.In("debug", "release")),
 	)
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type ApiKey struct {
+	mgm.DefaultModel `bson:",inline"`
+	User             primitive.ObjectID `json:"user" bson:"user"`
+	Name             string             `json:"name" bson:"name"`
+	Prefix           string             `json:"prefix" bson:"prefix"` // first characters of the key to recognize it
+	Hash             string             `json:"-" bson:"hash"`
+	Scopes           []string           `json:"scopes" bson:"scopes"`
+	ExpiresAt        *time.Time         `json:"expires_at" bson:"expires_at"`
+	LastUsedAt       *time.Time         `json:"last_used_at" bson:"last_used_at"`
+	Revoked          bool               `json:"revoked" bson:"revoked"`
+	Key              string             `json:"key,omitempty" bson:"-"` // plain key, only returned on creation
+}
+
+func NewApiKey(userId primitive.ObjectID, name string, prefix string, keyHash string, scopes []string, expiresAt *time.Time) *ApiKey {
+	return &ApiKey{
+		User:      userId,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      keyHash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		Revoked:   false,
+	}
+}
+
+func (model *ApiKey) CollectionName() string {
+	return "api_keys"
+}
+
+// You can override Collection functions or CRUD hooks
+// https://github.com/Kamva/mgm#a-models-hooks
+// https://github.com/Kamva/mgm#collections
@@ -3,27 +3,81 @@ package models
 import (
 	"github.com/kamva/mgm/v3"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"time"
 )
 
 type Note struct {
 	mgm.DefaultModel `bson:",inline"`
-	Author           primitive.ObjectID `json:"author" bson:"author"`
-	Title            string             `json:"title" bson:"title"`
-	Content          string             `json:"content" bson:"content"`
+	Author           primitive.ObjectID  `json:"author" bson:"author"`
+	Title            string              `json:"title" bson:"title"`
+	Content          string              `json:"content" bson:"content"`
+	Tags             []string            `json:"tags" bson:"tags"`
+	Notebook         *primitive.ObjectID `json:"notebook_id" bson:"notebook_id,omitempty"`
+	Version          int                 `json:"version" bson:"version"`
+	DeletedAt        *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
 
-func NewNote(author primitive.ObjectID, title string, content string) *Note {
+func NewNote(author primitive.ObjectID, title string, content string, tags []string, notebook *primitive.ObjectID) *Note {
 	return &Note{
-		Author:  author,
-		Title:   title,
-		Content: content,
+		Author:   author,
+		Title:    title,
+		Content:  content,
+		Tags:     NormalizeTags(tags),
+		Notebook: notebook,
+		Version:  1,
 	}
 }
 
+// CurrentVersion returns version of the note, notes created before versioning are version 1
+func (model *Note) CurrentVersion() int {
+	if model.Version < 1 {
+		return 1
+	}
+
+	return model.Version
+}
+
+// NormalizeTags trims and lowercases tags, duplicates are removed by keeping the order
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
 func (model *Note) CollectionName() string {
 	return "notes"
 }
 
+// NoteSearchResult is a note matched by a text search with its relevance score
+type NoteSearchResult struct {
+	Note       `bson:",inline"`
+	Score      float64        `json:"score" bson:"score"`
+	Highlights NoteHighlights `json:"highlights" bson:"-"`
+}
+
+// NoteHighlights has matched terms wrapped with <mark> tags, content is shortened to a snippet around the first match
+type NoteHighlights struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// TagCount is a tag of the user with count of its notes
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
 // You can override Collection functions or CRUD hooks
 // https://github.com/Kamva/mgm#a-models-hooks
 // https://github.com/Kamva/mgm#collections
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notebook groups notes of a user, notebooks can be nested up to NOTEBOOK_MAX_DEPTH
+type Notebook struct {
+	mgm.DefaultModel `bson:",inline"`
+	Author           primitive.ObjectID  `json:"author" bson:"author"`
+	Name             string              `json:"name" bson:"name"`
+	Parent           *primitive.ObjectID `json:"parent_id" bson:"parent"`
+	// Ancestors is the path from the top level notebook to the parent, it is used for subtree queries
+	Ancestors []primitive.ObjectID `json:"ancestors" bson:"ancestors"`
+}
+
+func NewNotebook(author primitive.ObjectID, name string, parent *Notebook) *Notebook {
+	notebook := &Notebook{
+		Author:    author,
+		Name:      name,
+		Ancestors: []primitive.ObjectID{},
+	}
+
+	if parent != nil {
+		notebook.Parent = &parent.ID
+		notebook.Ancestors = parent.Path()
+	}
+
+	return notebook
+}
+
+// Path returns ancestors of the notebook with itself, it is the ancestors of its children
+func (model *Notebook) Path() []primitive.ObjectID {
+	path := make([]primitive.ObjectID, 0, len(model.Ancestors)+1)
+	path = append(path, model.Ancestors...)
+	return append(path, model.ID)
+}
+
+// Depth is the level of the notebook, top level notebooks are 1
+func (model *Notebook) Depth() int {
+	return len(model.Ancestors) + 1
+}
+
+func (model *Notebook) CollectionName() string {
+	return "notebooks"
+}
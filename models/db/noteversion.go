@@ -0,0 +1,32 @@
+package models
+
+import (
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NoteVersion is a snapshot of a note before one of its updates
+type NoteVersion struct {
+	mgm.DefaultModel `bson:",inline"`
+	Note             primitive.ObjectID `json:"note_id" bson:"note"`
+	Author           primitive.ObjectID `json:"author" bson:"author"`
+	Version          int                `json:"version" bson:"version"`
+	Title            string             `json:"title" bson:"title"`
+	Content          string             `json:"content" bson:"content"`
+	Tags             []string           `json:"tags" bson:"tags"`
+}
+
+func NewNoteVersion(note *Note) *NoteVersion {
+	return &NoteVersion{
+		Note:    note.ID,
+		Author:  note.Author,
+		Version: note.CurrentVersion(),
+		Title:   note.Title,
+		Content: note.Content,
+		Tags:    note.Tags,
+	}
+}
+
+func (model *NoteVersion) CollectionName() string {
+	return "note_versions"
+}
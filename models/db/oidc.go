@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/kamva/mgm/v3"
+	"time"
+)
+
+// OIDCState is a pending OIDC login, it is deleted when the provider redirects back.
+// PKCE verifier is kept in a cookie of the browser which started the login, only its hash is saved.
+type OIDCState struct {
+	mgm.DefaultModel `bson:",inline"`
+	Provider         string    `json:"provider" bson:"provider"`
+	StateHash        string    `json:"-" bson:"state_hash"`
+	VerifierHash     string    `json:"-" bson:"verifier_hash"`
+	Nonce            string    `json:"-" bson:"nonce"`
+	ExpiresAt        time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+func NewOIDCState(provider string, stateHash string, verifierHash string, nonce string, expiresAt time.Time) *OIDCState {
+	return &OIDCState{
+		Provider:     provider,
+		StateHash:    stateHash,
+		VerifierHash: verifierHash,
+		Nonce:        nonce,
+		ExpiresAt:    expiresAt,
+	}
+}
+
+func (model *OIDCState) CollectionName() string {
+	return "oidc_states"
+}
+
+// You can override Collection functions or CRUD hooks
+// https://github.com/Kamva/mgm#a-models-hooks
+// https://github.com/Kamva/mgm#collections
@@ -0,0 +1,79 @@
+package models
+
+const (
+	PermissionNotesRead  = "notes:read"
+	PermissionNotesWrite = "notes:write"
+
+	PermissionUsersRead   = "users:read"
+	PermissionUsersManage = "users:manage"
+	PermissionUsersRole   = "users:role"
+	PermissionUsersDelete = "users:delete"
+)
+
+// ApiKeyScopes are permissions api keys can have, admin routes don't accept api keys
+var ApiKeyScopes = []string{
+	PermissionNotesRead,
+	PermissionNotesWrite,
+}
+
+// RolePermissions permission sets of roles
+var RolePermissions = map[string][]string{
+	RoleUser: {
+		PermissionNotesRead,
+		PermissionNotesWrite,
+	},
+	RoleModerator: {
+		PermissionNotesRead,
+		PermissionNotesWrite,
+		PermissionUsersRead,
+		PermissionUsersManage,
+	},
+	RoleAdmin: {
+		PermissionNotesRead,
+		PermissionNotesWrite,
+		PermissionUsersRead,
+		PermissionUsersManage,
+		PermissionUsersRole,
+		PermissionUsersDelete,
+	},
+}
+
+// roleLevels orders roles, users can manage only users with a lower role
+var roleLevels = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// IsValidRole checks if role is defined
+func IsValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission checks if role has the permission
+func RoleHasPermission(role string, permission string) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RoleOutranks checks if role is higher than the other role, unknown roles are the lowest
+func RoleOutranks(role string, other string) bool {
+	return roleLevels[role] > roleLevels[other]
+}
+
+// IsApiKeyScope checks if api keys can have the permission
+func IsApiKeyScope(permission string) bool {
+	for _, scope := range ApiKeyScopes {
+		if scope == permission {
+			return true
+		}
+	}
+
+	return false
+}
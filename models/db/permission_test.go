@@ -0,0 +1,41 @@
+package models
+
+import "testing"
+
+func TestRoleOutranks(t *testing.T) {
+	tests := []struct {
+		role     string
+		other    string
+		outranks bool
+	}{
+		{role: RoleAdmin, other: RoleModerator, outranks: true},
+		{role: RoleAdmin, other: RoleUser, outranks: true},
+		{role: RoleModerator, other: RoleUser, outranks: true},
+		{role: RoleModerator, other: "", outranks: true},
+		{role: RoleAdmin, other: RoleAdmin, outranks: false},
+		{role: RoleModerator, other: RoleAdmin, outranks: false},
+		{role: RoleUser, other: RoleUser, outranks: false},
+		{role: "unknown", other: RoleUser, outranks: false},
+	}
+
+	for _, test := range tests {
+		if RoleOutranks(test.role, test.other) != test.outranks {
+			t.Errorf("%q over %q: expected %v", test.role, test.other, test.outranks)
+		}
+	}
+}
+
+func TestIsApiKeyScope(t *testing.T) {
+	for _, permission := range []string{PermissionNotesRead, PermissionNotesWrite} {
+		if !IsApiKeyScope(permission) {
+			t.Errorf("expected %q to be an api key scope", permission)
+		}
+	}
+
+	// admin routes accept only tokens
+	for _, permission := range []string{PermissionUsersRead, PermissionUsersManage, PermissionUsersRole, PermissionUsersDelete} {
+		if IsApiKeyScope(permission) {
+			t.Errorf("expected %q not to be an api key scope", permission)
+		}
+	}
+}
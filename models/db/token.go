@@ -4,34 +4,84 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kamva/mgm/v3"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 const (
 	TokenTypeAccess  = "access"
 	TokenTypeRefresh = "refresh"
+
+	TokenTypeVerifyEmail   = "verify_email"
+	TokenTypeResetPassword = "reset_password"
+	TokenTypeChangeEmail   = "change_email"
+	TokenTypeMFAChallenge  = "mfa_challenge"
+	TokenTypeMagicLink     = "magic_link"
 )
 
 type Token struct {
 	mgm.DefaultModel `bson:",inline"`
 	User             primitive.ObjectID `json:"user" bson:"user"`
-	Token            string             `json:"token" bson:"token"`
+	Family           primitive.ObjectID `json:"family" bson:"family"`
+	Jti              string             `json:"jti" bson:"jti"`
+	Hash             string             `json:"-" bson:"hash"`
+	Token            string             `json:"token" bson:"-"` // plain token, never saved to db
 	Type             string             `json:"type" bson:"type"`
 	ExpiresAt        time.Time          `json:"expires_at" bson:"expires_at"`
 	Blacklisted      bool               `json:"blacklisted" bson:"blacklisted"`
+	Rotated          bool               `json:"rotated" bson:"rotated"`
+	Email            string             `json:"-" bson:"email,omitempty"` // new email of change_email tokens
+	Device           *TokenDevice       `json:"device,omitempty" bson:"device,omitempty"`
+	LastUsedAt       *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	Claims           *UserClaims        `json:"-" bson:"-"` // parsed claims of a verified token
+}
+
+// TokenDevice is the client which the access and refresh tokens are issued to
+type TokenDevice struct {
+	Name      string `json:"name" bson:"name"`
+	UserAgent string `json:"user_agent" bson:"user_agent"`
+	IP        string `json:"ip" bson:"ip"`
 }
 
 func (model *Token) GetResponseJson() gin.H {
 	return gin.H{"token": model.Token, "expires": model.ExpiresAt.Format("2006-01-02 15:04:05")}
 }
 
-func NewToken(userId primitive.ObjectID, tokenString string, tokenType string, expiresAt time.Time) *Token {
+func (model *Token) GetSessionJson(current bool) gin.H {
+	lastUsedAt := model.CreatedAt
+	if model.LastUsedAt != nil && model.LastUsedAt.After(lastUsedAt) {
+		lastUsedAt = *model.LastUsedAt
+	}
+
+	return gin.H{
+		"id":           model.ID,
+		"device":       model.Device,
+		"current":      current,
+		"created_at":   model.CreatedAt,
+		"last_used_at": lastUsedAt,
+		"expires_at":   model.ExpiresAt,
+	}
+}
+
+func NewTokenDevice(name string, userAgent string, ip string) *TokenDevice {
+	return &TokenDevice{
+		Name:      truncate(strings.TrimSpace(name), 64),
+		UserAgent: truncate(userAgent, 256),
+		IP:        ip,
+	}
+}
+
+func NewToken(userId primitive.ObjectID, family primitive.ObjectID, jti string, tokenHash string, tokenType string, expiresAt time.Time) *Token {
 	return &Token{
 		User:        userId,
-		Token:       tokenString,
+		Family:      family,
+		Jti:         jti,
+		Hash:        tokenHash,
 		Type:        tokenType,
 		ExpiresAt:   expiresAt,
 		Blacklisted: false,
+		Rotated:     false,
 	}
 }
 
@@ -39,6 +89,14 @@ func (model *Token) CollectionName() string {
 	return "tokens"
 }
 
+func truncate(value string, maxLength int) string {
+	if utf8.RuneCountInString(value) <= maxLength {
+		return value
+	}
+
+	return string([]rune(value)[:maxLength])
+}
+
 // You can override Collection functions or CRUD hooks
 // https://github.com/Kamva/mgm#a-models-hooks
 // https://github.com/Kamva/mgm#collections
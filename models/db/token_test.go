@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestGetSessionJsonHidesTokenSecrets(t *testing.T) {
+	token := NewToken(primitive.NewObjectID(), primitive.NewObjectID(), "secret-jti", "secret-hash", TokenTypeRefresh, time.Now())
+	token.Device = NewTokenDevice("laptop", "test agent", "127.0.0.1")
+
+	data, err := json.Marshal(token.GetSessionJson(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var session map[string]interface{}
+	if err = json.Unmarshal(data, &session); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"jti", "family", "hash", "token", "user", "blacklisted"} {
+		if _, ok := session[key]; ok {
+			t.Errorf("session json contains %q", key)
+		}
+	}
+	if session["current"] != false {
+		t.Errorf("expected current to be false, got %v", session["current"])
+	}
+}
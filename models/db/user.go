@@ -3,24 +3,42 @@ package models
 import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/kamva/mgm/v3"
+	"time"
 )
 
 const (
-	RoleUser = "user"
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
 )
 
 type User struct {
 	mgm.DefaultModel `bson:",inline"`
-	Email            string `json:"email" bson:"email"`
-	Password         string `json:"-" bson:"password"`
-	Name             string `json:"name" bson:"name"`
-	Role             string `json:"role" bson:"role"`
-	MailVerified     bool   `json:"mail_verified" bson:"mail_verified"`
+	Email            string         `json:"email" bson:"email"`
+	Password         string         `json:"-" bson:"password"`
+	Name             string         `json:"name" bson:"name"`
+	Role             string         `json:"role" bson:"role"`
+	MailVerified     bool           `json:"mail_verified" bson:"mail_verified"`
+	Disabled         bool           `json:"disabled" bson:"disabled"`
+	MFAEnabled       bool           `json:"mfa_enabled" bson:"mfa_enabled"`
+	MFASecret        string         `json:"-" bson:"mfa_secret,omitempty"`
+	MFALastStep      int64          `json:"-" bson:"mfa_last_step,omitempty"`
+	MFARecoveryCodes []string       `json:"-" bson:"mfa_recovery_codes,omitempty"` // SHA-256 hashes
+	Identities       []UserIdentity `json:"identities,omitempty" bson:"identities,omitempty"`
+	VerifyMailSentAt *time.Time     `json:"-" bson:"verify_mail_sent_at,omitempty"`
+	MagicLinkSentAt  *time.Time     `json:"-" bson:"magic_link_sent_at,omitempty"`
+}
+
+// UserIdentity is an account of the user at an external OIDC provider
+type UserIdentity struct {
+	Provider string `json:"provider" bson:"provider"`
+	Subject  string `json:"subject" bson:"subject"`
 }
 
 type UserClaims struct {
 	jwt.RegisteredClaims
 	Email string `json:"email"`
+	Role  string `json:"role"`
 	Type  string `json:"type"`
 }
 
@@ -31,6 +49,8 @@ func NewUser(email string, password string, name string, role string) *User {
 		Name:         name,
 		Role:         role,
 		MailVerified: false,
+		Disabled:     false,
+		MFAEnabled:   false,
 	}
 }
 
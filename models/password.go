@@ -0,0 +1,232 @@
+package models
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	PasswordRuleLength     = "length"
+	PasswordRuleWhitespace = "whitespace"
+	PasswordRuleEntropy    = "entropy"
+	PasswordRuleCommon     = "common"
+	PasswordRuleUserInput  = "user_input"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// PasswordPolicy checks new passwords, it is configured on startup with PASSWORD_* variables
+type PasswordPolicy struct {
+	MinLength       int
+	MaxLength       int
+	MinEntropy      float64 // bits
+	CommonPasswords map[string]bool
+}
+
+// PasswordPolicyFailure is the failed rule of a password with its reason
+type PasswordPolicyFailure struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError has every failed rule, it is returned as a list in validation errors
+type PasswordPolicyError []PasswordPolicyFailure
+
+func (e PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, failure := range e {
+		messages = append(messages, failure.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (e PasswordPolicyError) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]PasswordPolicyFailure(e))
+}
+
+var passwordPolicy = NewPasswordPolicy(8, 128, 40)
+
+// NewPasswordPolicy creates a policy with the bundled common password list
+func NewPasswordPolicy(minLength int, maxLength int, minEntropy float64) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		MinLength:       minLength,
+		MaxLength:       maxLength,
+		MinEntropy:      minEntropy,
+		CommonPasswords: map[string]bool{},
+	}
+	_ = policy.addCommonPasswords(strings.NewReader(commonPasswordList))
+
+	return policy
+}
+
+// LoadCommonPasswords adds passwords of a file with one password per line to the common password list
+func (p *PasswordPolicy) LoadCommonPasswords(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return p.addCommonPasswords(file)
+}
+
+func (p *PasswordPolicy) addCommonPasswords(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.CommonPasswords[strings.ToLower(line)] = true
+	}
+
+	return scanner.Err()
+}
+
+// SetPasswordPolicy replaces the policy of new passwords
+func SetPasswordPolicy(policy *PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+// CheckPassword checks a new password with the policy, userInputs are email, name, etc. of the user
+func CheckPassword(password string, userInputs ...string) error {
+	return passwordPolicy.Check(password, userInputs...)
+}
+
+// Check returns a PasswordPolicyError with every failed rule
+func (p *PasswordPolicy) Check(password string, userInputs ...string) error {
+	var failures PasswordPolicyError
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength || length > p.MaxLength {
+		failures = append(failures, PasswordPolicyFailure{
+			Rule:    PasswordRuleLength,
+			Message: "must be between " + strconv.Itoa(p.MinLength) + " and " + strconv.Itoa(p.MaxLength) + " characters",
+		})
+	}
+
+	if strings.IndexFunc(password, unicode.IsSpace) >= 0 {
+		failures = append(failures, PasswordPolicyFailure{
+			Rule:    PasswordRuleWhitespace,
+			Message: "cannot contain whitespaces",
+		})
+	}
+
+	if p.isCommon(password) {
+		failures = append(failures, PasswordPolicyFailure{
+			Rule:    PasswordRuleCommon,
+			Message: "is too common",
+		})
+	} else if PasswordEntropy(password) < p.MinEntropy {
+		failures = append(failures, PasswordPolicyFailure{
+			Rule:    PasswordRuleEntropy,
+			Message: "is too easy to guess, use a longer password with mixed characters",
+		})
+	}
+
+	if containsUserInput(password, userInputs) {
+		failures = append(failures, PasswordPolicyFailure{
+			Rule:    PasswordRuleUserInput,
+			Message: "cannot contain your email or name",
+		})
+	}
+
+	if len(failures) > 0 {
+		return failures
+	}
+
+	return nil
+}
+
+// isCommon checks the password and the password without digits and symbols at its end, e.g. "Password123!"
+func (p *PasswordPolicy) isCommon(password string) bool {
+	password = strings.ToLower(password)
+	if p.CommonPasswords[password] {
+		return true
+	}
+
+	base := strings.TrimRightFunc(password, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	return len(base) >= 4 && p.CommonPasswords[base]
+}
+
+// PasswordEntropy estimates bits of a password from its character pool,
+// repeated characters and sequences like "aaa" or "123" are not counted
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	effectiveLength := 0
+	var previous rune = -1
+
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+
+		if previous < 0 || (r != previous && r != previous+1 && r != previous-1) {
+			effectiveLength++
+		}
+		previous = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			pool += class.size
+		}
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	return float64(effectiveLength) * math.Log2(float64(pool))
+}
+
+// containsUserInput checks local part of the email, name and their words which have at least 3 characters
+func containsUserInput(password string, userInputs []string) bool {
+	password = strings.ToLower(password)
+
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if localPart, _, found := strings.Cut(input, "@"); found {
+			input = localPart
+		}
+
+		parts := strings.FieldsFunc(input, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '.' || r == '_' || r == '-' || r == '+'
+		})
+		parts = append(parts, input)
+
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) >= 3 && strings.Contains(password, part) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func failedRules(err error) []string {
+	var policyErr PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+
+	rules := make([]string, 0, len(policyErr))
+	for _, failure := range policyErr {
+		rules = append(rules, failure.Rule)
+	}
+
+	return rules
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := NewPasswordPolicy(8, 128, 40)
+
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		rules      []string
+	}{
+		{name: "strong", password: "Tr0ub4dor&3x", rules: nil},
+		{name: "strong unicode", password: "çılgınKedi42!", rules: nil},
+		{name: "too short", password: "Tr0u&3x", rules: []string{PasswordRuleLength}},
+		{name: "too long", password: strings.Repeat("Tr0ub4dor&3x", 11), rules: []string{PasswordRuleLength}},
+		{name: "length counts characters", password: "ğüşöçıĞÜ", rules: nil},
+		{name: "space", password: "Tr0ub4dor &3x", rules: []string{PasswordRuleWhitespace}},
+		{name: "tab", password: "Tr0ub4dor\t&3x", rules: []string{PasswordRuleWhitespace}},
+		{name: "common", password: "password", rules: []string{PasswordRuleCommon}},
+		{name: "common ignores case", password: "PassWord", rules: []string{PasswordRuleCommon}},
+		{name: "common with suffix", password: "Password123!", rules: []string{PasswordRuleCommon}},
+		{name: "short common base is not checked", password: "1234Xq9!zW", rules: nil},
+		{name: "low entropy", password: "qwzmxnck", rules: []string{PasswordRuleEntropy}},
+		{name: "enough entropy", password: "qwzmxnckv", rules: nil},
+		{name: "sequence", password: "abcdefghijklmnop", rules: []string{PasswordRuleEntropy}},
+		{name: "repeated", password: "zzzzzzzzzzzzzzzzzzzz", rules: []string{PasswordRuleEntropy}},
+		{name: "email", password: "johnsmith!42X", userInputs: []string{"johnsmith@example.com"}, rules: []string{PasswordRuleUserInput}},
+		{name: "name part", password: "Tr0ub4SMITH&3x", userInputs: []string{"a@example.com", "John Smith"}, rules: []string{PasswordRuleUserInput}},
+		{name: "short name part", password: "Tr0ub4Al&3x", userInputs: []string{"a@example.com", "Al Smith"}, rules: nil},
+		{
+			name:     "every failed rule",
+			password: "john 1",
+			userInputs: []string{
+				"john@example.com",
+			},
+			rules: []string{PasswordRuleLength, PasswordRuleWhitespace, PasswordRuleEntropy, PasswordRuleUserInput},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := policy.Check(test.password, test.userInputs...)
+			if test.rules == nil {
+				if err != nil {
+					t.Fatalf("expected password to pass, got %v", err)
+				}
+				return
+			}
+
+			if rules := failedRules(err); !reflect.DeepEqual(rules, test.rules) {
+				t.Fatalf("expected rules %v, got %v (%v)", test.rules, rules, err)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyErrorJSON(t *testing.T) {
+	policy := NewPasswordPolicy(8, 128, 40)
+	err := policy.Check("pass wd")
+
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+
+	expected := `[{"rule":"length","message":"must be between 8 and 128 characters"},` +
+		`{"rule":"whitespace","message":"cannot contain whitespaces"},` +
+		`{"rule":"entropy","message":"is too easy to guess, use a longer password with mixed characters"}]`
+	if string(data) != expected {
+		t.Fatalf("unexpected json %s", data)
+	}
+
+	if err.Error() != "must be between 8 and 128 characters; cannot contain whitespaces; "+
+		"is too easy to guess, use a longer password with mixed characters" {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+}
+
+func TestRegisterRequestPasswordReasons(t *testing.T) {
+	previous := passwordPolicy
+	SetPasswordPolicy(NewPasswordPolicy(8, 128, 40))
+	defer SetPasswordPolicy(previous)
+
+	err := RegisterRequest{Name: "John Smith", Email: "john@example.com", Password: "Password1"}.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	data, _ := json.Marshal(err)
+	var errs map[string][]PasswordPolicyFailure
+	if jsonErr := json.Unmarshal(data, &errs); jsonErr != nil {
+		t.Fatalf("expected structured password reasons, got %s", data)
+	}
+	if len(errs["password"]) != 1 || errs["password"][0].Rule != PasswordRuleCommon {
+		t.Fatalf("unexpected password reasons %s", data)
+	}
+}
+
+func TestLoadCommonPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("# comment\n\nCorrectHorseBatteryStaple\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := NewPasswordPolicy(8, 128, 40)
+	if err := policy.Check("correcthorsebatterystaple"); err != nil {
+		t.Fatalf("expected password to pass before loading the list, got %v", err)
+	}
+	if err := policy.LoadCommonPasswords(path); err != nil {
+		t.Fatal(err)
+	}
+	if rules := failedRules(policy.Check("correcthorsebatterystaple")); !reflect.DeepEqual(rules, []string{PasswordRuleCommon}) {
+		t.Fatalf("expected loaded password to be common, got %v", rules)
+	}
+	if policy.CommonPasswords["# comment"] {
+		t.Fatal("expected comments to be skipped")
+	}
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	tests := []struct {
+		password string
+		min      float64
+		max      float64
+	}{
+		{password: "", min: 0, max: 0},
+		{password: "aaaa", min: 4.7, max: 4.71},   // one effective character of 26
+		{password: "abcd", min: 4.7, max: 4.71},   // sequence
+		{password: "azaz", min: 18.8, max: 18.81}, // 4 x log2(26)
+		{password: "aZ9!", min: 26.2, max: 26.3},  // 4 x log2(95)
+		{password: "ğüşö", min: 26.5, max: 26.6},  // 4 x log2(100)
+	}
+
+	for _, test := range tests {
+		if entropy := PasswordEntropy(test.password); entropy < test.min || entropy > test.max {
+			t.Errorf("%q: expected entropy between %.2f and %.2f, got %.2f", test.password, test.min, test.max, entropy)
+		}
+	}
+}
@@ -1,15 +1,24 @@
 package models
 
 import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 	"regexp"
 )
 
-var passwordRule = []validation.Rule{
-	validation.Required,
-	validation.Length(8, 32),
-	validation.Match(regexp.MustCompile("^\\S+$")).Error("cannot contain whitespaces"),
+// passwordRules checks new passwords with the password policy, userInputs are email, name, etc. of the user
+func passwordRules(userInputs ...string) []validation.Rule {
+	return []validation.Rule{
+		validation.Required,
+		validation.By(func(value interface{}) error {
+			password, _ := value.(string)
+			if password == "" {
+				return nil
+			}
+			return CheckPassword(password, userInputs...)
+		}),
+	}
 }
 
 type RegisterRequest struct {
@@ -22,7 +31,7 @@ func (a RegisterRequest) Validate() error {
 	return validation.ValidateStruct(&a,
 		validation.Field(&a.Name, validation.Required, validation.Length(3, 64)),
 		validation.Field(&a.Email, validation.Required, is.Email),
-		validation.Field(&a.Password, passwordRule...),
+		validation.Field(&a.Password, passwordRules(a.Email, a.Name)...),
 	)
 }
 
@@ -32,9 +41,10 @@ type LoginRequest struct {
 }
 
 func (a LoginRequest) Validate() error {
+	// password policy is not checked, passwords created with older policies have to work
 	return validation.ValidateStruct(&a,
 		validation.Field(&a.Email, validation.Required, is.Email),
-		validation.Field(&a.Password, passwordRule...),
+		validation.Field(&a.Password, validation.Required),
 	)
 }
 
@@ -52,14 +62,295 @@ func (a RefreshRequest) Validate() error {
 	)
 }
 
+type LogoutRequest struct {
+	Token string `json:"token"`
+}
+
+func (a LogoutRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile("^\\S+$")).Error("cannot contain whitespaces"),
+		),
+	)
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (a VerifyEmailRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile("^\\S+$")).Error("cannot contain whitespaces"),
+		),
+	)
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+func (a OIDCCallbackRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Code, validation.Required, validation.Length(1, 2048)),
+		validation.Field(&a.State, validation.Required, validation.Length(1, 256)),
+	)
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+func (a ForgotPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Email, validation.Required, is.Email),
+	)
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+func (a MagicLinkRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Email, validation.Required, is.Email),
+	)
+}
+
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token"`
+}
+
+func (a MagicLinkVerifyRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile("^\\S+$")).Error("cannot contain whitespaces"),
+		),
+	)
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (a ResetPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile("^\\S+$")).Error("cannot contain whitespaces"),
+		),
+		validation.Field(&a.Password, passwordRules()...),
+	)
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password"`
+}
+
+func (a ChangePasswordRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.CurrentPassword, validation.Required),
+		validation.Field(&a.Password, passwordRules()...),
+	)
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (a ChangeEmailRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Email, validation.Required, is.Email),
+		validation.Field(&a.Password, validation.Required),
+	)
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token"`
+}
+
+func (a ConfirmEmailChangeRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile("^\\S+$")).Error("cannot contain whitespaces"),
+		),
+	)
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (a SetRoleRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Role, validation.Required, validation.In(db.RoleUser, db.RoleModerator, db.RoleAdmin)),
+	)
+}
+
+var mfaCodeRule = []validation.Rule{
+	validation.Required,
+	validation.Length(6, 17),
+	validation.Match(regexp.MustCompile("^[0-9a-zA-Z-]+$")).Error("must be a code or a recovery code"),
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (a MFACodeRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Code, validation.Required, is.Digit, validation.Length(6, 6)),
+	)
+}
+
+type MFAVerifyRequest struct {
+	Token string `json:"mfa_token"`
+	Code  string `json:"code"`
+}
+
+func (a MFAVerifyRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(
+			&a.Token,
+			validation.Required,
+			validation.Match(regexp.MustCompile("^\\S+$")).Error("cannot contain whitespaces"),
+		),
+		validation.Field(&a.Code, mfaCodeRule...),
+	)
+}
+
+// MFADisableRequest users without password, e.g. created with OIDC login, send a TOTP or recovery code
+type MFADisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (a MFADisableRequest) Validate() error {
+	var passwordRules []validation.Rule
+	var codeRules []validation.Rule
+	if a.Code == "" {
+		passwordRules = append(passwordRules, validation.Required)
+	} else {
+		codeRules = mfaCodeRule
+	}
+
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Password, passwordRules...),
+		validation.Field(&a.Code, codeRules...),
+	)
+}
+
+type CreateApiKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+func (a CreateApiKeyRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Name, validation.Required, validation.Length(1, 64)),
+		validation.Field(&a.Scopes, validation.Required, validation.Each(validation.In(
+			db.PermissionNotesRead,
+			db.PermissionNotesWrite,
+		))),
+		validation.Field(&a.ExpiresInDays, validation.Min(0), validation.Max(3650)),
+	)
+}
+
+var tagRules = []validation.Rule{
+	validation.Required,
+	validation.RuneLength(1, 32),
+	validation.Match(regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)).Error("can contain only letters, digits, _ and -"),
+}
+
+// NoteRequest keeps tags and notebook of the note on update if they are not sent, an empty tag list removes tags
 type NoteRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Tags       []string `json:"tags"`
+	NotebookId string   `json:"notebook_id"`
 }
 
 func (a NoteRequest) Validate() error {
 	return validation.ValidateStruct(&a,
 		validation.Field(&a.Title, validation.Required),
 		validation.Field(&a.Content, validation.Required),
+		validation.Field(&a.Tags, validation.Length(0, 20), validation.Each(tagRules...)),
+		validation.Field(&a.NotebookId, is.MongoID),
+	)
+}
+
+// MoveNoteRequest moves a note to the notebook, an empty notebook_id moves it out of notebooks
+type MoveNoteRequest struct {
+	NotebookId string `json:"notebook_id"`
+}
+
+func (a MoveNoteRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.NotebookId, is.MongoID),
+	)
+}
+
+// NotebookRequest creates or updates a notebook, an empty parent_id makes it a top level notebook
+type NotebookRequest struct {
+	Name     string `json:"name"`
+	ParentId string `json:"parent_id"`
+}
+
+func (a NotebookRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Name, validation.Required, validation.RuneLength(1, 100)),
+		validation.Field(&a.ParentId, is.MongoID),
+	)
+}
+
+// NoteFilterRequest is the query of note list, e.g. ?tag=a&tag=b&match=all
+type NoteFilterRequest struct {
+	Tags  []string `json:"tag"`
+	Match string   `json:"match"`
+}
+
+func (a NoteFilterRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Tags, validation.Length(0, 20), validation.Each(tagRules...)),
+		validation.Field(&a.Match, validation.In("any", "all")),
+	)
+}
+
+// RenameTagRequest has the current tag from path and its new name from body
+type RenameTagRequest struct {
+	Tag  string `json:"-"`
+	Name string `json:"name"`
+}
+
+func (a RenameTagRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Tag, tagRules...),
+		validation.Field(&a.Name, tagRules...),
+	)
+}
+
+type MergeTagsRequest struct {
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+}
+
+func (a MergeTagsRequest) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Sources, validation.Required, validation.Length(1, 20), validation.Each(tagRules...)),
+		validation.Field(&a.Target, tagRules...),
 	)
 }
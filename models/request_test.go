@@ -0,0 +1,20 @@
+package models
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"testing"
+)
+
+func TestCreateApiKeyRequestScopes(t *testing.T) {
+	valid := CreateApiKeyRequest{Name: "ci", Scopes: []string{db.PermissionNotesRead, db.PermissionNotesWrite}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected notes scopes to be valid, got %v", err)
+	}
+
+	for _, scope := range []string{db.PermissionUsersRead, db.PermissionUsersManage, db.PermissionUsersRole, db.PermissionUsersDelete, "notes:*"} {
+		request := CreateApiKeyRequest{Name: "ci", Scopes: []string{scope}}
+		if err := request.Validate(); err == nil {
+			t.Errorf("expected scope %q to be rejected", scope)
+		}
+	}
+}
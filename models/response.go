@@ -2,6 +2,7 @@ package models
 
 import (
 	"github.com/gin-gonic/gin"
+	validation "github.com/go-ozzo/ozzo-validation"
 	"net/http"
 )
 
@@ -26,6 +27,19 @@ func SendResponseData(c *gin.Context, data gin.H) {
 	response.SendResponse(c)
 }
 
+// SendValidationErrorResponse sends 400 with field errors in data, e.g. every failed password policy rule
+func SendValidationErrorResponse(c *gin.Context, err error) {
+	response := &Response{
+		StatusCode: http.StatusBadRequest,
+		Success:    false,
+		Message:    err.Error(),
+	}
+	if errs, ok := err.(validation.Errors); ok {
+		response.Data = gin.H{"errors": errs}
+	}
+	response.SendResponse(c)
+}
+
 func SendErrorResponse(c *gin.Context, status int, message string) {
 	response := &Response{
 		StatusCode: status,
@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/controllers"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares/validators"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoute(router *gin.RouterGroup, handlers ...gin.HandlerFunc) {
+	admin := router.Group("/admin", handlers...)
+	{
+		admin.GET(
+			"/users",
+			middlewares.RequirePermission(db.PermissionUsersRead),
+			validators.GetUsersValidator(),
+			controllers.AdminGetUsers,
+		)
+
+		admin.GET(
+			"/users/:id",
+			middlewares.RequirePermission(db.PermissionUsersRead),
+			validators.PathIdValidator(),
+			controllers.AdminGetOneUser,
+		)
+
+		admin.PUT(
+			"/users/:id/disable",
+			middlewares.RequirePermission(db.PermissionUsersManage),
+			validators.PathIdValidator(),
+			controllers.AdminDisableUser,
+		)
+
+		admin.PUT(
+			"/users/:id/enable",
+			middlewares.RequirePermission(db.PermissionUsersManage),
+			validators.PathIdValidator(),
+			controllers.AdminEnableUser,
+		)
+
+		admin.POST(
+			"/users/:id/logout",
+			middlewares.RequirePermission(db.PermissionUsersManage),
+			validators.PathIdValidator(),
+			controllers.AdminLogoutUser,
+		)
+
+		admin.PUT(
+			"/users/:id/role",
+			middlewares.RequirePermission(db.PermissionUsersRole),
+			validators.PathIdValidator(),
+			validators.SetRoleValidator(),
+			controllers.AdminSetUserRole,
+		)
+
+		admin.DELETE(
+			"/users/:id",
+			middlewares.RequirePermission(db.PermissionUsersDelete),
+			validators.PathIdValidator(),
+			controllers.AdminDeleteUser,
+		)
+	}
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/controllers"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares"
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares/validators"
 	"github.com/gin-gonic/gin"
 )
@@ -26,5 +27,97 @@ func AuthRoute(router *gin.RouterGroup) {
 			validators.RefreshValidator(),
 			controllers.Refresh,
 		)
+
+		auth.POST(
+			"/magic-link",
+			validators.MagicLinkValidator(),
+			controllers.MagicLink,
+		)
+
+		auth.POST(
+			"/magic-link/verify",
+			validators.MagicLinkVerifyValidator(),
+			controllers.VerifyMagicLink,
+		)
+
+		auth.POST(
+			"/verify-email",
+			validators.VerifyEmailValidator(),
+			controllers.VerifyEmail,
+		)
+
+		auth.POST(
+			"/verify-email/resend",
+			middlewares.JWTMiddleware(),
+			controllers.ResendVerificationEmail,
+		)
+
+		auth.POST(
+			"/forgot-password",
+			validators.ForgotPasswordValidator(),
+			controllers.ForgotPassword,
+		)
+
+		auth.POST(
+			"/reset-password",
+			validators.ResetPasswordValidator(),
+			controllers.ResetPassword,
+		)
+
+		auth.POST(
+			"/change-email/confirm",
+			validators.ConfirmEmailChangeValidator(),
+			controllers.ConfirmEmailChange,
+		)
+
+		auth.GET(
+			"/oidc/:provider",
+			controllers.OIDCAuthorize,
+		)
+
+		auth.POST(
+			"/oidc/:provider/callback",
+			validators.OIDCCallbackValidator(),
+			controllers.OIDCCallback,
+		)
+
+		auth.POST(
+			"/mfa/verify",
+			validators.MFAVerifyValidator(),
+			controllers.VerifyMFA,
+		)
+
+		auth.POST(
+			"/mfa/totp/setup",
+			middlewares.JWTMiddleware(),
+			controllers.SetupTOTP,
+		)
+
+		auth.POST(
+			"/mfa/totp/confirm",
+			middlewares.JWTMiddleware(),
+			validators.MFACodeValidator(),
+			controllers.ConfirmTOTP,
+		)
+
+		auth.POST(
+			"/mfa/disable",
+			middlewares.JWTMiddleware(),
+			validators.MFADisableValidator(),
+			controllers.DisableMFA,
+		)
+
+		auth.POST(
+			"/logout",
+			middlewares.JWTMiddleware(),
+			validators.LogoutValidator(),
+			controllers.Logout,
+		)
+
+		auth.POST(
+			"/logout-all",
+			middlewares.JWTMiddleware(),
+			controllers.LogoutAll,
+		)
 	}
 }
@@ -2,7 +2,9 @@ package routes
 
 import (
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/controllers"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares"
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares/validators"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,31 +13,102 @@ func NoteRoute(router *gin.RouterGroup, handlers ...gin.HandlerFunc) {
 	{
 		notes.POST(
 			"",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
 			validators.CreateNoteValidator(),
 			controllers.CreateNewNote,
 		)
 
 		notes.GET(
 			"",
+			middlewares.RequirePermission(db.PermissionNotesRead),
 			validators.GetNotesValidator(),
 			controllers.GetNotes,
 		)
 
+		notes.GET(
+			"/trash",
+			middlewares.RequirePermission(db.PermissionNotesRead),
+			validators.GetNotesValidator(),
+			controllers.GetTrashedNotes,
+		)
+
+		notes.DELETE(
+			"/trash",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			controllers.EmptyTrash,
+		)
+
+		notes.DELETE(
+			"/trash/:id",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.PathIdValidator(),
+			controllers.DeleteTrashedNote,
+		)
+
+		notes.GET(
+			"/search",
+			middlewares.RequirePermission(db.PermissionNotesRead),
+			validators.SearchNotesValidator(),
+			controllers.SearchNotes,
+		)
+
 		notes.GET(
 			"/:id",
+			middlewares.RequirePermission(db.PermissionNotesRead),
 			validators.PathIdValidator(),
 			controllers.GetOneNote,
 		)
 
 		notes.PUT(
 			"/:id",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
 			validators.PathIdValidator(),
 			validators.UpdateNoteValidator(),
 			controllers.UpdateNote,
 		)
 
+		notes.GET(
+			"/:id/versions",
+			middlewares.RequirePermission(db.PermissionNotesRead),
+			validators.PathIdValidator(),
+			validators.GetNotesValidator(),
+			controllers.GetNoteVersions,
+		)
+
+		notes.GET(
+			"/:id/versions/diff",
+			middlewares.RequirePermission(db.PermissionNotesRead),
+			validators.PathIdValidator(),
+			validators.NoteVersionDiffValidator(),
+			controllers.DiffNoteVersions,
+		)
+
+		notes.POST(
+			"/:id/versions/:version/restore",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.PathIdValidator(),
+			validators.PathVersionValidator(),
+			controllers.RestoreNoteVersion,
+		)
+
+		notes.POST(
+			"/:id/restore",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.PathIdValidator(),
+			controllers.RestoreNote,
+		)
+
+		notes.PUT(
+			"/:id/notebook",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.PathIdValidator(),
+			validators.MoveNoteValidator(),
+			controllers.MoveNote,
+		)
+
 		notes.DELETE(
 			"/:id",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
 			validators.PathIdValidator(),
 			controllers.DeleteNote,
 		)
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/controllers"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares/validators"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/gin-gonic/gin"
+)
+
+func NotebookRoute(router *gin.RouterGroup, handlers ...gin.HandlerFunc) {
+	notebooks := router.Group("/notebooks", handlers...)
+	{
+		notebooks.POST(
+			"",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.NotebookValidator(),
+			controllers.CreateNotebook,
+		)
+
+		notebooks.GET(
+			"",
+			middlewares.RequirePermission(db.PermissionNotesRead),
+			controllers.GetNotebooks,
+		)
+
+		notebooks.GET(
+			"/:id",
+			middlewares.RequirePermission(db.PermissionNotesRead),
+			validators.PathIdValidator(),
+			controllers.GetOneNotebook,
+		)
+
+		notebooks.GET(
+			"/:id/notes",
+			middlewares.RequirePermission(db.PermissionNotesRead),
+			validators.PathIdValidator(),
+			validators.GetNotesValidator(),
+			controllers.GetNotebookNotes,
+		)
+
+		notebooks.PUT(
+			"/:id",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.PathIdValidator(),
+			validators.NotebookValidator(),
+			controllers.UpdateNotebook,
+		)
+
+		notebooks.DELETE(
+			"/:id",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.PathIdValidator(),
+			validators.DeleteNotebookValidator(),
+			controllers.DeleteNotebook,
+		)
+	}
+}
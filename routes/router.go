@@ -18,14 +18,21 @@ func New() *gin.Engine {
 	r.Use(gin.LoggerWithWriter(middlewares.LogWriter()))
 	r.Use(gin.CustomRecovery(middlewares.AppRecovery()))
 	r.Use(middlewares.CORSMiddleware())
+	r.Use(middlewares.CSRFMiddleware())
 
 	v1 := r.Group("/v1")
 	{
 		PingRoute(v1)
 		AuthRoute(v1)
-		NoteRoute(v1, middlewares.JWTMiddleware())
+		UserRoute(v1, middlewares.JWTMiddleware())
+		AdminRoute(v1, middlewares.JWTMiddleware())
+		NoteRoute(v1, middlewares.AuthMiddleware(), middlewares.VerifiedMailMiddleware())
+		TagRoute(v1, middlewares.AuthMiddleware(), middlewares.VerifiedMailMiddleware())
+		NotebookRoute(v1, middlewares.AuthMiddleware(), middlewares.VerifiedMailMiddleware())
 	}
 
+	WellKnownRoute(&r.RouterGroup)
+
 	docs.SwaggerInfo.BasePath = v1.BasePath() // adds /v1 to swagger base path
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/controllers"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares/validators"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/gin-gonic/gin"
+)
+
+func TagRoute(router *gin.RouterGroup, handlers ...gin.HandlerFunc) {
+	tags := router.Group("/tags", handlers...)
+	{
+		tags.GET(
+			"",
+			middlewares.RequirePermission(db.PermissionNotesRead),
+			controllers.GetTags,
+		)
+
+		tags.PUT(
+			"/:tag",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.RenameTagValidator(),
+			controllers.RenameTag,
+		)
+
+		tags.POST(
+			"/merge",
+			middlewares.RequirePermission(db.PermissionNotesWrite),
+			validators.MergeTagsValidator(),
+			controllers.MergeTags,
+		)
+	}
+}
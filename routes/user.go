@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/controllers"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/middlewares/validators"
+	"github.com/gin-gonic/gin"
+)
+
+func UserRoute(router *gin.RouterGroup, handlers ...gin.HandlerFunc) {
+	users := router.Group("/users", handlers...)
+	{
+		users.PUT(
+			"/me/password",
+			validators.ChangePasswordValidator(),
+			controllers.ChangePassword,
+		)
+
+		users.PUT(
+			"/me/email",
+			validators.ChangeEmailValidator(),
+			controllers.ChangeEmail,
+		)
+
+		users.GET(
+			"/me/sessions",
+			controllers.GetSessions,
+		)
+
+		users.DELETE(
+			"/me/sessions/:id",
+			validators.PathIdValidator(),
+			controllers.RevokeSession,
+		)
+
+		users.POST(
+			"/me/api-keys",
+			validators.CreateApiKeyValidator(),
+			controllers.CreateApiKey,
+		)
+
+		users.GET(
+			"/me/api-keys",
+			controllers.GetApiKeys,
+		)
+
+		users.DELETE(
+			"/me/api-keys/:id",
+			validators.PathIdValidator(),
+			controllers.RevokeApiKey,
+		)
+	}
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func WellKnownRoute(router *gin.RouterGroup) {
+	wellKnown := router.Group("/.well-known")
+	{
+		wellKnown.GET(
+			"/jwks.json",
+			controllers.JWKS,
+		)
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+const (
+	apiKeyPrefix        = "ak_"
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateApiKey creates a scoped api key, scopes must be api key scopes and permissions of user's role.
+// Only the hash is saved, plain key is returned once.
+func CreateApiKey(user *db.User, name string, scopes []string, expiresInDays int) (*db.ApiKey, error) {
+	for _, scope := range scopes {
+		if !db.IsApiKeyScope(scope) {
+			return nil, errors.New("api keys cannot have scope: " + scope)
+		}
+		if !db.RoleHasPermission(user.Role, scope) {
+			return nil, errors.New("you don't have permission: " + scope)
+		}
+	}
+
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, errors.New("cannot create api key")
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	var expiresAt *time.Time
+	if expiresInDays > 0 {
+		expires := time.Now().Add(time.Duration(expiresInDays) * time.Hour * 24)
+		expiresAt = &expires
+	}
+
+	apiKey := db.NewApiKey(user.ID, name, key[:len(apiKeyPrefix)+6], hashToken(key), scopes, expiresAt)
+	err := mgm.Coll(apiKey).Create(apiKey)
+	if err != nil {
+		return nil, errors.New("cannot create api key")
+	}
+	apiKey.Key = key
+
+	return apiKey, nil
+}
+
+// GetApiKeys lists not revoked api keys of the user
+func GetApiKeys(userId primitive.ObjectID) ([]db.ApiKey, error) {
+	var apiKeys []db.ApiKey
+	err := mgm.Coll(&db.ApiKey{}).SimpleFind(
+		&apiKeys,
+		bson.M{"user": userId, "revoked": false},
+		options.Find().SetSort(bson.M{"created_at": -1}),
+	)
+	if err != nil {
+		return nil, errors.New("cannot find api keys")
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeApiKey revokes an api key of the user
+func RevokeApiKey(userId primitive.ObjectID, apiKeyId primitive.ObjectID) error {
+	updateResult, err := mgm.Coll(&db.ApiKey{}).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: apiKeyId, "user": userId, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil || updateResult.MatchedCount <= 0 {
+		return errors.New("cannot revoke api key")
+	}
+
+	return nil
+}
+
+// RevokeUserApiKeys revokes every api key of the user, used when credentials of the user may be compromised
+func RevokeUserApiKeys(userId primitive.ObjectID) error {
+	_, err := mgm.Coll(&db.ApiKey{}).UpdateMany(
+		mgm.Ctx(),
+		bson.M{"user": userId, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil {
+		return errors.New("cannot revoke api keys")
+	}
+
+	return nil
+}
+
+// VerifyApiKey finds a not revoked and not expired api key
+func VerifyApiKey(key string) (*db.ApiKey, error) {
+	apiKey := &db.ApiKey{}
+	err := mgm.Coll(apiKey).First(
+		bson.M{
+			"hash":    hashToken(key),
+			"revoked": false,
+			"$or": bson.A{
+				bson.M{"expires_at": nil},
+				bson.M{"expires_at": bson.M{"$gt": time.Now()}},
+			},
+		},
+		apiKey,
+	)
+	if err != nil {
+		return nil, errors.New("not valid api key")
+	}
+
+	// last use is saved at most once a minute like sessions
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		go touchApiKey(apiKey.ID)
+	}
+
+	return apiKey, nil
+}
+
+func touchApiKey(apiKeyId primitive.ObjectID) {
+	now := time.Now()
+	_, _ = mgm.Coll(&db.ApiKey{}).UpdateOne(
+		mgm.Ctx(),
+		bson.M{
+			field.ID: apiKeyId,
+			"$or": bson.A{
+				bson.M{"last_used_at": nil},
+				bson.M{"last_used_at": bson.M{"$lt": now.Add(-apiKeyTouchInterval)}},
+			},
+		},
+		bson.M{"$set": bson.M{"last_used_at": now}},
+	)
+}
+
+// EnsureApiKeyIndexes creates the unique index of api key hashes which keys are verified by
+func EnsureApiKeyIndexes() {
+	_, err := mgm.Coll(&db.ApiKey{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetName("api_keys_hash").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("api_keys_user"),
+		},
+	})
+
+	if err != nil {
+		log.Println("cannot create api key indexes:", err)
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"time"
+)
+
+func TestCreateApiKeyScopes(t *testing.T) {
+	useTestDatabase(t)
+
+	user, err := CreateUser("Admin", "admin@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Role = db.RoleAdmin
+
+	if _, err = CreateApiKey(user, "ci", []string{db.PermissionNotesRead, db.PermissionNotesWrite}, 0); err != nil {
+		t.Fatalf("expected notes scopes to be accepted, got %v", err)
+	}
+	if _, err = CreateApiKey(user, "admin", []string{db.PermissionUsersRead}, 0); err == nil {
+		t.Fatal("expected users scope to be rejected even for admins")
+	}
+
+	user.Role = ""
+	if _, err = CreateApiKey(user, "ci", []string{db.PermissionNotesWrite}, 0); err == nil {
+		t.Fatal("expected scope which is not a permission of the role to be rejected")
+	}
+}
+
+func TestTouchApiKeyThrottle(t *testing.T) {
+	useTestDatabase(t)
+
+	user, err := CreateUser("Test", "touch@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := CreateApiKey(user, "ci", []string{db.PermissionNotesRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastUsedAt := func() time.Time {
+		saved := &db.ApiKey{}
+		if err := mgm.Coll(saved).FindByID(apiKey.ID, saved); err != nil {
+			t.Fatal(err)
+		}
+		if saved.LastUsedAt == nil {
+			return time.Time{}
+		}
+		return *saved.LastUsedAt
+	}
+
+	touchApiKey(apiKey.ID)
+	first := lastUsedAt()
+	if first.IsZero() {
+		t.Fatal("expected first use to be saved")
+	}
+
+	touchApiKey(apiKey.ID)
+	if !lastUsedAt().Equal(first) {
+		t.Fatal("expected use in the same minute not to be saved")
+	}
+
+	_, err = mgm.Coll(apiKey).UpdateByID(mgm.Ctx(), apiKey.ID, bson.M{"$set": bson.M{"last_used_at": time.Now().Add(-apiKeyTouchInterval)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	touchApiKey(apiKey.ID)
+	if !lastUsedAt().After(first) {
+		t.Fatal("expected use after a minute to be saved")
+	}
+}
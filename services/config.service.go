@@ -3,6 +3,7 @@ package services
 import (
 	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
 	"github.com/spf13/viper"
+	"strings"
 )
 
 var Config *models.EnvConfig
@@ -12,6 +13,34 @@ func LoadConfig() {
 	v.AutomaticEnv()
 	v.SetDefault("SERVER_PORT", "8080")
 	v.SetDefault("MODE", "debug")
+	v.SetDefault("JWT_ALGORITHM", "HS256")
+	v.SetDefault("JWT_LEGACY_HEADER", false)
+	v.SetDefault("COOKIE_SECURE", true)
+	v.SetDefault("COOKIE_SAMESITE", "lax")
+	v.SetDefault("APP_URL", "http://localhost:8080")
+	v.SetDefault("MAIL_DRIVER", "log")
+	v.SetDefault("MAIL_FROM", "no-reply@localhost.localdomain")
+	v.SetDefault("MAIL_LOG_PATH", "logs/mail.log")
+	v.SetDefault("SMTP_PORT", "587")
+	v.SetDefault("VERIFY_EMAIL_EXPIRATION_HOURS", 24)
+	v.SetDefault("VERIFY_EMAIL_RESEND_SECONDS", 60)
+	v.SetDefault("RESET_PASSWORD_EXPIRATION_MINUTES", 60)
+	v.SetDefault("MAGIC_LINK_EXPIRATION_MINUTES", 15)
+	v.SetDefault("LOGIN_MAX_ATTEMPTS", 10)
+	v.SetDefault("LOGIN_MAX_IP_ATTEMPTS", 100)
+	v.SetDefault("LOGIN_LOCKOUT_MINUTES", 15)
+	v.SetDefault("MFA_ISSUER", "GoLang Rest API Starter")
+	v.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	v.SetDefault("PASSWORD_MAX_LENGTH", 128)
+	v.SetDefault("PASSWORD_MIN_ENTROPY", 40)
+	v.SetDefault("PASSWORD_HASHER", "argon2id")
+	v.SetDefault("ARGON2_MEMORY_KIB", 19456)
+	v.SetDefault("ARGON2_ITERATIONS", 2)
+	v.SetDefault("ARGON2_PARALLELISM", 1)
+	v.SetDefault("BCRYPT_COST", 10)
+	v.SetDefault("NOTEBOOK_MAX_DEPTH", 5)
+	v.SetDefault("NOTE_VERSION_MAX_COUNT", 50)
+	v.SetDefault("TRASH_RETENTION_DAYS", 30)
 	v.SetConfigType("dotenv")
 	v.SetConfigName(".env")
 	v.AddConfigPath("./")
@@ -24,7 +53,48 @@ func LoadConfig() {
 		panic(err)
 	}
 
+	Config.OIDC = loadOIDCProviderConfigs(v, Config.OIDCProviders)
+
 	if err := Config.Validate(); err != nil {
 		panic(err)
 	}
+
+	loadPasswordPolicy()
+}
+
+// loadOIDCProviderConfigs reads OIDC_<NAME>_* variables of comma separated provider names
+func loadOIDCProviderConfigs(v *viper.Viper, providers string) map[string]models.OIDCProviderConfig {
+	configs := map[string]models.OIDCProviderConfig{}
+	for _, name := range strings.Split(providers, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		v.SetDefault(prefix+"SCOPES", "openid email profile")
+		v.SetDefault(prefix+"REDIRECT_URL", strings.TrimSuffix(v.GetString("APP_URL"), "/")+"/oidc/"+name+"/callback")
+
+		configs[name] = models.OIDCProviderConfig{
+			Issuer:       v.GetString(prefix + "ISSUER"),
+			ClientId:     v.GetString(prefix + "CLIENT_ID"),
+			ClientSecret: v.GetString(prefix + "CLIENT_SECRET"),
+			RedirectUrl:  v.GetString(prefix + "REDIRECT_URL"),
+			Scopes:       v.GetString(prefix + "SCOPES"),
+		}
+	}
+
+	return configs
+}
+
+// loadPasswordPolicy configures the password policy of new passwords
+func loadPasswordPolicy() {
+	policy := models.NewPasswordPolicy(Config.PasswordMinLength, Config.PasswordMaxLength, float64(Config.PasswordMinEntropy))
+	if Config.PasswordBlocklistPath != "" {
+		if err := policy.LoadCommonPasswords(Config.PasswordBlocklistPath); err != nil {
+			panic(err)
+		}
+	}
+
+	models.SetPasswordPolicy(policy)
 }
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFTokenCookie    = "csrf_token"
+	CSRFTokenHeader    = "X-CSRF-Token"
+
+	// OIDCVerifierCookie binds a pending OIDC login to the browser which started it
+	OIDCVerifierCookie = "oidc_verifier"
+
+	// refresh token is only sent to refresh and logout routes
+	refreshTokenCookiePath = "/v1/auth"
+	oidcCookiePath         = "/v1/auth/oidc"
+)
+
+// SetAuthCookies sets tokens as HttpOnly cookies with a CSRF cookie readable by the client,
+// returns the CSRF token which has to be sent back in X-CSRF-Token header
+func SetAuthCookies(c *gin.Context, accessToken *db.Token, refreshToken *db.Token) (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", errors.New("cannot create csrf token")
+	}
+	csrfToken := base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	setCookie(c, AccessTokenCookie, accessToken.Token, "/", accessToken.ExpiresAt, true)
+	setCookie(c, RefreshTokenCookie, refreshToken.Token, refreshTokenCookiePath, refreshToken.ExpiresAt, true)
+	setCookie(c, CSRFTokenCookie, csrfToken, "/", refreshToken.ExpiresAt, false)
+
+	return csrfToken, nil
+}
+
+// ClearAuthCookies removes token and CSRF cookies
+func ClearAuthCookies(c *gin.Context) {
+	setCookie(c, AccessTokenCookie, "", "/", time.Unix(0, 0), true)
+	setCookie(c, RefreshTokenCookie, "", refreshTokenCookiePath, time.Unix(0, 0), true)
+	setCookie(c, CSRFTokenCookie, "", "/", time.Unix(0, 0), false)
+}
+
+// GetTokenCookie reads a token cookie, cookies are ignored if cookie mode is disabled
+func GetTokenCookie(c *gin.Context, name string) string {
+	if !Config.CookieMode {
+		return ""
+	}
+
+	value, err := c.Cookie(name)
+	if err != nil {
+		return ""
+	}
+
+	return value
+}
+
+// SetOIDCVerifierCookie keeps PKCE verifier of a pending OIDC login, it is set in every mode
+// since the login is always started in a browser
+func SetOIDCVerifierCookie(c *gin.Context, codeVerifier string) {
+	setCookie(c, OIDCVerifierCookie, codeVerifier, oidcCookiePath, time.Now().Add(oidcStateExpiresIn), true)
+}
+
+// PopOIDCVerifierCookie reads and removes PKCE verifier cookie, the login can be finished only once
+func PopOIDCVerifierCookie(c *gin.Context) string {
+	value, err := c.Cookie(OIDCVerifierCookie)
+	if err != nil {
+		return ""
+	}
+
+	setCookie(c, OIDCVerifierCookie, "", oidcCookiePath, time.Unix(0, 0), true)
+	return value
+}
+
+func setCookie(c *gin.Context, name string, value string, path string, expires time.Time, httpOnly bool) {
+	maxAge := int(time.Until(expires).Seconds())
+	if maxAge <= 0 {
+		maxAge = -1
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   Config.CookieDomain,
+		Expires:  expires,
+		MaxAge:   maxAge,
+		Secure:   Config.CookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: cookieSameSite(),
+	})
+}
+
+func cookieSameSite() http.SameSite {
+	switch Config.CookieSameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
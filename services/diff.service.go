@@ -0,0 +1,224 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	diffContextLines = 3
+	// texts with more changed lines are shown as replaced completely to limit memory of the diff
+	diffMaxEdits = 2000
+)
+
+type diffLine struct {
+	kind byte   // ' ', '-' or '+'
+	text string // ends with a newline except the last line of a text without one
+}
+
+// UnifiedDiff returns a line based unified diff of two texts, it is empty if texts are equal
+func UnifiedDiff(fromName string, toName string, from string, to string) string {
+	if from == to {
+		return ""
+	}
+
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	var builder strings.Builder
+	builder.WriteString("--- " + fromName + "\n")
+	builder.WriteString("+++ " + toName + "\n")
+
+	// fromLine and toLine are 0-based positions of lines[i] in both texts
+	fromLine, toLine := 0, 0
+	for i := 0; i < len(lines); {
+		if lines[i].kind == ' ' {
+			i++
+			fromLine++
+			toLine++
+			continue
+		}
+
+		// a hunk starts with context lines before the change and grows while changes are close
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].kind != ' ' {
+				end++
+				continue
+			}
+
+			next := end
+			for next < len(lines) && lines[next].kind == ' ' && next-end < 2*diffContextLines {
+				next++
+			}
+			if next < len(lines) && lines[next].kind != ' ' {
+				end = next
+				continue
+			}
+			end += diffContextLines
+			if end > len(lines) {
+				end = len(lines)
+			}
+			break
+		}
+
+		hunkFrom, hunkTo := fromLine-(i-start), toLine-(i-start)
+		fromCount, toCount := 0, 0
+		var hunk strings.Builder
+		for _, line := range lines[start:end] {
+			hunk.WriteByte(line.kind)
+			hunk.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				hunk.WriteString("\n\\ No newline at end of file\n")
+			}
+			if line.kind != '+' {
+				fromCount++
+			}
+			if line.kind != '-' {
+				toCount++
+			}
+		}
+
+		builder.WriteString("@@ -" + hunkRange(hunkFrom, fromCount) + " +" + hunkRange(hunkTo, toCount) + " @@\n")
+		builder.WriteString(hunk.String())
+
+		fromLine, toLine = hunkFrom+fromCount, hunkTo+toCount
+		i = end
+	}
+
+	return builder.String()
+}
+
+// hunkRange formats 0-based start of a hunk, empty ranges point to the line before them
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return strconv.Itoa(start) + ",0"
+	}
+
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(count)
+}
+
+// splitLines keeps newlines in lines, so a last line without newline differs from the same line with it
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// diffLines finds the shortest edit script of two line lists with Myers' algorithm
+func diffLines(a []string, b []string) []diffLine {
+	// common prefix and suffix are trimmed to keep the edit graph small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{kind: ' ', text: text})
+	}
+	lines = append(lines, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{kind: ' ', text: text})
+	}
+
+	return lines
+}
+
+func myersDiff(a []string, b []string) []diffLine {
+	n, m := len(a), len(b)
+	maxEdits := n + m
+	if maxEdits > diffMaxEdits {
+		maxEdits = diffMaxEdits
+	}
+
+	// v[k+offset] is the furthest x on diagonal k, trace keeps v[-d-1..d+1] of every step for backtracking
+	offset := maxEdits + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	for d := 0; d <= maxEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace)
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, n+m)
+	for _, text := range a {
+		lines = append(lines, diffLine{kind: '-', text: text})
+	}
+	for _, text := range b {
+		lines = append(lines, diffLine{kind: '+', text: text})
+	}
+
+	return lines
+}
+
+func backtrackDiff(a []string, b []string, trace [][]int) []diffLine {
+	var reversed []diffLine
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var previousK int
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := v[previousK+d+1]
+		previousY := previousX - previousK
+
+		for x > previousX && y > previousY {
+			reversed = append(reversed, diffLine{kind: ' ', text: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == previousX {
+				reversed = append(reversed, diffLine{kind: '+', text: b[y-1]})
+			} else {
+				reversed = append(reversed, diffLine{kind: '-', text: a[x-1]})
+			}
+		}
+
+		x, y = previousX, previousY
+	}
+
+	lines := make([]diffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+
+	return lines
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const noNewlineMarker = "\\ No newline at end of file\n"
+
+// applyUnifiedDiff applies hunks of a unified diff to the from text, positions, counts and context of hunks are checked
+func applyUnifiedDiff(from string, diff string) (string, error) {
+	if diff == "" {
+		return from, nil
+	}
+
+	lines := splitLines(diff)
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "--- ") || !strings.HasPrefix(lines[1], "+++ ") {
+		return "", errors.New("missing header or hunks")
+	}
+
+	source := splitLines(from)
+	var result []string
+	position := 0
+	for i := 2; i < len(lines); {
+		var fromStart, fromCount, toStart, toCount int
+		if _, err := fmt.Sscanf(lines[i], "@@ -%d,%d +%d,%d @@", &fromStart, &fromCount, &toStart, &toCount); err != nil {
+			return "", fmt.Errorf("bad hunk header %q", lines[i])
+		}
+		i++
+
+		// empty ranges point to the line before them
+		if fromCount > 0 {
+			fromStart--
+		}
+		if toCount > 0 {
+			toStart--
+		}
+		if fromStart < position || fromStart > len(source) {
+			return "", fmt.Errorf("hunk starts at %d after line %d", fromStart, position)
+		}
+		result = append(result, source[position:fromStart]...)
+		position = fromStart
+		if toStart != len(result) {
+			return "", fmt.Errorf("hunk starts at %d in new text, expected %d", toStart, len(result))
+		}
+
+		fromSeen, toSeen := 0, 0
+		for i < len(lines) && !strings.HasPrefix(lines[i], "@@ ") {
+			if lines[i] == noNewlineMarker {
+				return "", errors.New("marker without a line")
+			}
+			kind, text := lines[i][0], lines[i][1:]
+			i++
+			if i < len(lines) && lines[i] == noNewlineMarker {
+				text = strings.TrimSuffix(text, "\n")
+				i++
+			}
+
+			switch kind {
+			case ' ', '-':
+				if position >= len(source) || source[position] != text {
+					return "", fmt.Errorf("line %d does not match %q", position+1, text)
+				}
+				position++
+				fromSeen++
+				if kind == ' ' {
+					result = append(result, text)
+					toSeen++
+				}
+			case '+':
+				result = append(result, text)
+				toSeen++
+			default:
+				return "", fmt.Errorf("bad line %q", lines[i-1])
+			}
+		}
+
+		if fromSeen != fromCount || toSeen != toCount {
+			return "", fmt.Errorf("hunk has %d,%d lines, header has %d,%d", fromSeen, toSeen, fromCount, toCount)
+		}
+	}
+
+	result = append(result, source[position:]...)
+	return strings.Join(result, ""), nil
+}
+
+// longestCommonLines is the length of the longest common subsequence of two line lists
+func longestCommonLines(a []string, b []string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				current[j+1] = previous[j] + 1
+			} else if previous[j+1] > current[j] {
+				current[j+1] = previous[j+1]
+			} else {
+				current[j+1] = current[j]
+			}
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func randomDiffText(random *rand.Rand) string {
+	lines := make([]string, random.Intn(12))
+	for i := range lines {
+		lines[i] = string(rune('a' + random.Intn(4)))
+	}
+
+	text := strings.Join(lines, "\n")
+	if text != "" && random.Intn(2) == 0 {
+		text += "\n"
+	}
+
+	return text
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{name: "equal", from: "a\nb\n", to: "a\nb\n", expected: ""},
+		{
+			name:     "changed line",
+			from:     "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:       "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+			expected: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:     "far changes are separate hunks",
+			from:     "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			to:       "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{name: "from empty", from: "", to: "a\n", expected: "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+a\n"},
+		{name: "to empty", from: "a\n", to: "", expected: "--- a\n+++ b\n@@ -1,1 +0,0 @@\n-a\n"},
+		{
+			name:     "newline removed",
+			from:     "x\n",
+			to:       "x",
+			expected: "--- a\n+++ b\n@@ -1,1 +1,1 @@\n-x\n+x\n" + noNewlineMarker,
+		},
+		{
+			name:     "newline added",
+			from:     "a\nx",
+			to:       "a\nx\n",
+			expected: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-x\n" + noNewlineMarker + "+x\n",
+		},
+		{
+			name:     "context without newline",
+			from:     "a\nb",
+			to:       "c\nb",
+			expected: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-a\n+c\n b\n" + noNewlineMarker,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := UnifiedDiff("a", "b", test.from, test.to); diff != test.expected {
+				t.Fatalf("expected diff\n%s\ngot\n%s", test.expected, diff)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20000; i++ {
+		from, to := randomDiffText(random), randomDiffText(random)
+		diff := UnifiedDiff("from", "to", from, to)
+
+		if (diff == "") != (from == to) {
+			t.Fatalf("%q -> %q: unexpected diff %q", from, to, diff)
+		}
+		applied, err := applyUnifiedDiff(from, diff)
+		if err != nil {
+			t.Fatalf("%q -> %q: %v\n%s", from, to, err, diff)
+		}
+		if applied != to {
+			t.Fatalf("%q -> %q: diff applies as %q\n%s", from, to, applied, diff)
+		}
+
+		a, b := splitLines(from), splitLines(to)
+		edits := 0
+		for _, line := range diffLines(a, b) {
+			if line.kind != ' ' {
+				edits++
+			}
+		}
+		if minimum := len(a) + len(b) - 2*longestCommonLines(a, b); edits != minimum {
+			t.Fatalf("%q -> %q: expected %d edits, got %d", from, to, minimum, edits)
+		}
+	}
+}
+
+func TestUnifiedDiffOverMaxEdits(t *testing.T) {
+	var from, to strings.Builder
+	for i := 0; i < diffMaxEdits; i++ {
+		from.WriteString("from " + strconv.Itoa(i) + "\n")
+		to.WriteString("to " + strconv.Itoa(i) + "\n")
+	}
+	// common lines around the changes are still kept as context
+	fromText, toText := "first\n"+from.String()+"last\n", "first\n"+to.String()+"last\n"
+
+	diff := UnifiedDiff("from", "to", fromText, toText)
+	applied, err := applyUnifiedDiff(fromText, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != toText {
+		t.Fatal("expected diff over max edits to replace the changed lines")
+	}
+	if !strings.HasPrefix(diff, "--- from\n+++ to\n@@ -1,2002 +1,2002 @@\n first\n-from 0\n") {
+		t.Fatalf("unexpected diff start %q", diff[:80])
+	}
+}
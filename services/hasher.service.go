@@ -0,0 +1,195 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"sync"
+)
+
+const (
+	PasswordHasherArgon2id = "argon2id"
+	PasswordHasherBcrypt   = "bcrypt"
+)
+
+// PasswordHasher hashes passwords into encoded strings which carry the algorithm and its parameters
+type PasswordHasher interface {
+	Hash(plainPassword string) (string, error)
+	Verify(encodedHash string, plainPassword string) bool
+	// Identify reports whether the encoded hash is produced by this algorithm
+	Identify(encodedHash string) bool
+	// NeedsRehash reports whether the encoded hash is produced with other parameters
+	NeedsRehash(encodedHash string) bool
+}
+
+// Argon2idHasher encodes hashes in PHC string format, e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func (h *Argon2idHasher) Hash(plainPassword string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(plainPassword), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(encodedHash string, plainPassword string) bool {
+	params, salt, key, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return false
+	}
+
+	otherKey := argon2.IDKey([]byte(plainPassword), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
+func (h *Argon2idHasher) Identify(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$argon2id$")
+}
+
+func (h *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, salt, key, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.Memory || params.Iterations != h.Iterations || params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength || uint32(len(key)) != h.KeyLength
+}
+
+func decodeArgon2idHash(encodedHash string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	params := &Argon2idHasher{}
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errors.New("not valid argon2id key")
+	}
+
+	return params, salt, key, nil
+}
+
+// BcryptHasher is kept to verify hashes created before argon2id. Bcrypt uses only first 72 bytes of passwords,
+// longer passwords are hashed with SHA-256 first, so every byte of them counts.
+type BcryptHasher struct {
+	Cost int
+}
+
+const bcryptMaxPasswordLength = 72
+
+func (h *BcryptHasher) Hash(plainPassword string) (string, error) {
+	password, err := bcrypt.GenerateFromPassword(bcryptPassword(plainPassword), h.Cost)
+	return string(password), err
+}
+
+func (h *BcryptHasher) Verify(encodedHash string, plainPassword string) bool {
+	if bcrypt.CompareHashAndPassword([]byte(encodedHash), bcryptPassword(plainPassword)) == nil {
+		return true
+	}
+
+	// hashes of long passwords imported from other bcrypt implementations are created with their first 72 bytes
+	return len(plainPassword) > bcryptMaxPasswordLength &&
+		bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(plainPassword[:bcryptMaxPasswordLength])) == nil
+}
+
+func (h *BcryptHasher) Identify(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$")
+}
+
+func (h *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost != h.Cost
+}
+
+func bcryptPassword(plainPassword string) []byte {
+	if len(plainPassword) <= bcryptMaxPasswordLength {
+		return []byte(plainPassword)
+	}
+
+	sum := sha256.Sum256([]byte(plainPassword))
+	return []byte(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+var passwordHasher PasswordHasher
+var passwordHashers []PasswordHasher
+var passwordHasherOnce sync.Once
+
+// GetPasswordHasher returns the hasher of new passwords which is set with PASSWORD_HASHER
+func GetPasswordHasher() PasswordHasher {
+	loadPasswordHashers()
+	return passwordHasher
+}
+
+// SetPasswordHasher replaces the hasher of new passwords, useful for tests with cheap parameters
+func SetPasswordHasher(h PasswordHasher) {
+	loadPasswordHashers()
+	passwordHasher = h
+	passwordHashers = append([]PasswordHasher{h}, passwordHashers...)
+}
+
+func loadPasswordHashers() {
+	passwordHasherOnce.Do(func() {
+		argon2idHasher := &Argon2idHasher{
+			Memory:      uint32(Config.Argon2MemoryKiB),
+			Iterations:  uint32(Config.Argon2Iterations),
+			Parallelism: uint8(Config.Argon2Parallelism),
+			SaltLength:  16,
+			KeyLength:   32,
+		}
+		bcryptHasher := &BcryptHasher{Cost: Config.BcryptCost}
+
+		passwordHasher = argon2idHasher
+		if Config.PasswordHasher == PasswordHasherBcrypt {
+			passwordHasher = bcryptHasher
+		}
+		passwordHashers = []PasswordHasher{argon2idHasher, bcryptHasher}
+	})
+}
+
+// findPasswordHasher detects the algorithm of an encoded hash
+func findPasswordHasher(encodedHash string) (PasswordHasher, bool) {
+	loadPasswordHashers()
+	for _, h := range passwordHashers {
+		if h.Identify(encodedHash) {
+			return h, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+// testArgon2idHasher uses cheap parameters to keep tests fast
+func testArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+// useTestPasswordHasher replaces the hasher of new passwords for the duration of the test
+func useTestPasswordHasher(t *testing.T, h PasswordHasher) {
+	t.Helper()
+
+	useTestConfig(t)
+	loadPasswordHashers()
+	previous, previousHashers := passwordHasher, passwordHashers
+	passwordHasher = h
+	passwordHashers = []PasswordHasher{testArgon2idHasher(), &BcryptHasher{Cost: bcrypt.MinCost}}
+	t.Cleanup(func() {
+		passwordHasher, passwordHashers = previous, previousHashers
+	})
+}
+
+func TestArgon2idHasherRoundTrip(t *testing.T) {
+	hasher := testArgon2idHasher()
+
+	encoded, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("unexpected PHC string %s", encoded)
+	}
+	if !hasher.Identify(encoded) {
+		t.Fatal("expected hash to be identified")
+	}
+	if !hasher.Verify(encoded, "correct horse battery staple") {
+		t.Fatal("expected password to be verified")
+	}
+	if hasher.Verify(encoded, "correct horse battery stapler") {
+		t.Fatal("expected wrong password to be rejected")
+	}
+	if hasher.NeedsRehash(encoded) {
+		t.Fatal("expected hash with same parameters not to need rehash")
+	}
+
+	other, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == encoded {
+		t.Fatal("expected hashes of the same password to have different salts")
+	}
+
+	stronger := testArgon2idHasher()
+	stronger.Iterations = 2
+	if !stronger.NeedsRehash(encoded) {
+		t.Fatal("expected hash with other parameters to need rehash")
+	}
+	if !stronger.Verify(encoded, "correct horse battery staple") {
+		t.Fatal("expected parameters to be read from the hash")
+	}
+}
+
+func TestArgon2idHasherMalformed(t *testing.T) {
+	hasher := testArgon2idHasher()
+	valid, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, "$")
+	salt, key := parts[4], parts[5]
+
+	tests := map[string]string{
+		"empty":          "",
+		"missing key":    "$argon2id$v=19$m=64,t=1,p=1$" + salt,
+		"extra part":     valid + "$extra",
+		"argon2i":        "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key,
+		"old version":    "$argon2id$v=16$m=64,t=1,p=1$" + salt + "$" + key,
+		"no version":     "$argon2id$m=64,t=1,p=1$" + salt + "$" + key,
+		"bad parameters": "$argon2id$v=19$m=x,t=1,p=1$" + salt + "$" + key,
+		"bad salt":       "$argon2id$v=19$m=64,t=1,p=1$not*base64$" + key,
+		"bad key":        "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$not*base64",
+		"empty key":      "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$",
+		"padded key":     "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + key + "=",
+	}
+
+	for name, encoded := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, _, err := decodeArgon2idHash(encoded); err == nil {
+				t.Fatal("expected decode error")
+			}
+			if hasher.Verify(encoded, "password") {
+				t.Fatal("expected malformed hash to be rejected")
+			}
+			if !hasher.NeedsRehash(encoded) {
+				t.Fatal("expected malformed hash to need rehash")
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	argon2idHasher := testArgon2idHasher()
+	useTestPasswordHasher(t, argon2idHasher)
+
+	bcryptHash, err := (&BcryptHasher{Cost: bcrypt.MinCost}).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2idHash, err := argon2idHasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakerHasher := testArgon2idHasher()
+	weakerHasher.Memory = 32
+	weakerHash, err := weakerHasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hash   string
+		rehash bool
+		hasher string
+	}{
+		{name: "bcrypt", hash: bcryptHash, rehash: true, hasher: PasswordHasherBcrypt},
+		{name: "argon2id with current parameters", hash: argon2idHash, rehash: false, hasher: PasswordHasherArgon2id},
+		{name: "argon2id with other parameters", hash: weakerHash, rehash: true, hasher: PasswordHasherArgon2id},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if passwordNeedsRehash(test.hash) != test.rehash {
+				t.Fatalf("expected rehash %v", test.rehash)
+			}
+
+			hasher, ok := findPasswordHasher(test.hash)
+			if !ok {
+				t.Fatal("expected hasher to be found")
+			}
+			if _, isBcrypt := hasher.(*BcryptHasher); isBcrypt != (test.hasher == PasswordHasherBcrypt) {
+				t.Fatalf("expected %s hasher, got %T", test.hasher, hasher)
+			}
+			if !hasher.Verify(test.hash, "password") {
+				t.Fatal("expected password to be verified by the found hasher")
+			}
+		})
+	}
+
+	if _, ok := findPasswordHasher(""); ok {
+		t.Fatal("expected no hasher for users without password")
+	}
+}
+
+func TestBcryptHasherLongPassword(t *testing.T) {
+	hasher := &BcryptHasher{Cost: bcrypt.MinCost}
+
+	// passwords up to PASSWORD_MAX_LENGTH characters are longer than 72 bytes of bcrypt
+	password := strings.Repeat("a", 72) + strings.Repeat("ü", 56)
+	encoded, err := hasher.Hash(password)
+	if err != nil {
+		t.Fatalf("expected long password to be hashed: %v", err)
+	}
+
+	if !hasher.Verify(encoded, password) {
+		t.Fatal("expected long password to be verified")
+	}
+	if hasher.Verify(encoded, strings.Repeat("a", 72)+strings.Repeat("ö", 56)) {
+		t.Fatal("expected password which differs after 72 bytes to be rejected")
+	}
+	if hasher.Verify(encoded, strings.Repeat("a", 72)) {
+		t.Fatal("expected first 72 bytes of the password to be rejected")
+	}
+
+	// hashes of other implementations use first 72 bytes
+	truncated, err := bcrypt.GenerateFromPassword([]byte(password[:72]), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasher.Verify(string(truncated), password) {
+		t.Fatal("expected truncated legacy hash to be verified")
+	}
+}
+
+func TestBcryptHasherNeedsRehash(t *testing.T) {
+	hasher := &BcryptHasher{Cost: bcrypt.MinCost}
+	encoded, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasher.Identify(encoded) || hasher.NeedsRehash(encoded) {
+		t.Fatal("expected hash with same cost to be identified without rehash")
+	}
+	if !(&BcryptHasher{Cost: bcrypt.MinCost + 1}).NeedsRehash(encoded) {
+		t.Fatal("expected hash with other cost to need rehash")
+	}
+	if hasher.Identify("$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5") {
+		t.Fatal("expected argon2id hash not to be identified as bcrypt")
+	}
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const noteSnippetLength = 160
+
+type textRange struct {
+	start int
+	end   int
+}
+
+// HighlightNote marks terms of a text search query in title and content of the note
+func HighlightNote(note *db.Note, query string) db.NoteHighlights {
+	terms := searchTerms(query)
+
+	return db.NoteHighlights{
+		Title:   highlightText([]rune(note.Title), terms),
+		Content: highlightSnippet([]rune(note.Content), terms, noteSnippetLength),
+	}
+}
+
+// searchTerms returns words and phrases of a text search query, negated ones like -word or -"a phrase" are skipped
+func searchTerms(query string) []string {
+	var terms []string
+	rest := query
+
+	for rest != "" {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		negated := strings.HasPrefix(rest, "-")
+		if negated {
+			rest = rest[1:]
+		}
+
+		var term string
+		if strings.HasPrefix(rest, "\"") {
+			phrase, after, _ := strings.Cut(rest[1:], "\"")
+			term, rest = strings.TrimSpace(phrase), after
+		} else {
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return unicode.IsSpace(r) || r == '"'
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			term = strings.TrimFunc(rest[:end], func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			rest = rest[end:]
+		}
+
+		if term != "" && !negated {
+			terms = append(terms, strings.ToLower(term))
+		}
+	}
+
+	return terms
+}
+
+// textWord is a word of a text with its rune positions, lower and stem are used for matching
+type textWord struct {
+	start int
+	end   int
+	lower []rune
+	stem  []rune
+}
+
+// findTerms returns sorted and merged ranges of terms in the text. Matching is case-insensitive and on word boundaries,
+// single words also match other forms of them like text search does, e.g. "run" matches "running".
+func findTerms(text []rune, terms []string) []textRange {
+	words := splitWords(text)
+
+	var ranges []textRange
+	for _, term := range terms {
+		termWords := splitWords([]rune(term))
+		if len(termWords) == 0 {
+			continue
+		}
+
+		if len(termWords) == 1 {
+			for _, word := range words {
+				if equalRunes(word.stem, termWords[0].stem) {
+					ranges = append(ranges, textRange{start: word.start, end: word.end})
+				}
+			}
+			continue
+		}
+
+		// words of phrases are matched exactly
+		for i := 0; i+len(termWords) <= len(words); i++ {
+			matched := true
+			for j, termWord := range termWords {
+				if !equalRunes(words[i+j].lower, termWord.lower) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				ranges = append(ranges, textRange{start: words[i].start, end: words[i+len(termWords)-1].end})
+			}
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+
+	var merged []textRange
+	for _, r := range ranges {
+		last := len(merged) - 1
+		if last >= 0 && r.start <= merged[last].end {
+			if r.end > merged[last].end {
+				merged[last].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// splitWords finds runs of letters and digits in the text
+func splitWords(text []rune) []textWord {
+	var words []textWord
+	for i := 0; i < len(text); {
+		if !isWordRune(text[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(text) && isWordRune(text[i]) {
+			i++
+		}
+
+		lower := make([]rune, i-start)
+		for j, r := range text[start:i] {
+			lower[j] = unicode.ToLower(r)
+		}
+		words = append(words, textWord{start: start, end: i, lower: lower, stem: stemWord(lower)})
+	}
+
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func equalRunes(a []rune, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// stemWord removes plural and -ed, -ing suffixes of a lowercase word with step 1 of the Porter stemmer,
+// it is close enough to the stemmer of text search to highlight the words it matched
+func stemWord(word []rune) []rune {
+	if len(word) <= 2 {
+		return word
+	}
+	stem := append([]rune(nil), word...)
+
+	switch {
+	case hasRuneSuffix(stem, "sses"), hasRuneSuffix(stem, "ies"):
+		stem = stem[:len(stem)-2]
+	case hasRuneSuffix(stem, "ss"):
+	case hasRuneSuffix(stem, "s"):
+		stem = stem[:len(stem)-1]
+	}
+
+	removed := false
+	switch {
+	case hasRuneSuffix(stem, "eed"):
+		if wordMeasure(stem[:len(stem)-3]) > 0 {
+			stem = stem[:len(stem)-1]
+		}
+	case hasRuneSuffix(stem, "ed") && hasVowel(stem[:len(stem)-2]):
+		stem, removed = stem[:len(stem)-2], true
+	case hasRuneSuffix(stem, "ing") && hasVowel(stem[:len(stem)-3]):
+		stem, removed = stem[:len(stem)-3], true
+	}
+
+	if removed {
+		last := len(stem) - 1
+		switch {
+		case hasRuneSuffix(stem, "at"), hasRuneSuffix(stem, "bl"), hasRuneSuffix(stem, "iz"):
+			stem = append(stem, 'e')
+		case last > 0 && stem[last] == stem[last-1] && isConsonant(stem, last) &&
+			stem[last] != 'l' && stem[last] != 's' && stem[last] != 'z':
+			stem = stem[:last]
+		case wordMeasure(stem) == 1 && endsWithCVC(stem):
+			stem = append(stem, 'e')
+		}
+	}
+
+	if hasRuneSuffix(stem, "y") && hasVowel(stem[:len(stem)-1]) {
+		stem[len(stem)-1] = 'i'
+	}
+
+	return stem
+}
+
+func hasRuneSuffix(word []rune, suffix string) bool {
+	suffixRunes := []rune(suffix)
+	if len(word) < len(suffixRunes) {
+		return false
+	}
+
+	return equalRunes(word[len(word)-len(suffixRunes):], suffixRunes)
+}
+
+// isConsonant reports whether the letter at i is a consonant, y after a consonant is a vowel
+func isConsonant(word []rune, i int) bool {
+	switch word[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(word, i-1)
+	default:
+		return true
+	}
+}
+
+func hasVowel(word []rune) bool {
+	for i := range word {
+		if !isConsonant(word, i) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wordMeasure counts vowel-consonant sequences of a word, m in [C](VC){m}[V]
+func wordMeasure(word []rune) int {
+	i := 0
+	for i < len(word) && isConsonant(word, i) {
+		i++
+	}
+
+	measure := 0
+	for i < len(word) {
+		for i < len(word) && !isConsonant(word, i) {
+			i++
+		}
+		if i >= len(word) {
+			break
+		}
+		for i < len(word) && isConsonant(word, i) {
+			i++
+		}
+		measure++
+	}
+
+	return measure
+}
+
+// endsWithCVC checks consonant-vowel-consonant ending where the last consonant is not w, x or y, e.g. "hop"
+func endsWithCVC(word []rune) bool {
+	n := len(word)
+	if n < 3 || !isConsonant(word, n-3) || isConsonant(word, n-2) || !isConsonant(word, n-1) {
+		return false
+	}
+
+	return word[n-1] != 'w' && word[n-1] != 'x' && word[n-1] != 'y'
+}
+
+// highlightText escapes the text and wraps terms with <mark> tags
+func highlightText(text []rune, terms []string) string {
+	var builder strings.Builder
+	position := 0
+
+	for _, r := range findTerms(text, terms) {
+		builder.WriteString(html.EscapeString(string(text[position:r.start])))
+		builder.WriteString("<mark>")
+		builder.WriteString(html.EscapeString(string(text[r.start:r.end])))
+		builder.WriteString("</mark>")
+		position = r.end
+	}
+	builder.WriteString(html.EscapeString(string(text[position:])))
+
+	return builder.String()
+}
+
+// highlightSnippet cuts a part of the text around the first term, or its beginning if no term is found
+func highlightSnippet(text []rune, terms []string, length int) string {
+	if len(text) <= length {
+		return highlightText(text, terms)
+	}
+
+	start := 0
+	if ranges := findTerms(text, terms); len(ranges) > 0 {
+		// shows some context before the match
+		start = ranges[0].start - length/4
+		if start < 0 {
+			start = 0
+		}
+		if start+length > len(text) {
+			start = len(text) - length
+		}
+	}
+
+	end := start + length
+
+	// avoids cutting words at both ends
+	if start > 0 {
+		if i := indexOfSpace(text[start:end]); i >= 0 && i < length/4 {
+			start += i + 1
+		}
+	}
+	if end < len(text) {
+		if i := lastIndexOfSpace(text[start:end]); i > length/2 {
+			end = start + i
+		}
+	}
+
+	snippet := highlightText(text[start:end], terms)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+
+	return snippet
+}
+
+func indexOfSpace(text []rune) int {
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func lastIndexOfSpace(text []rune) int {
+	for i := len(text) - 1; i >= 0; i-- {
+		if unicode.IsSpace(text[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
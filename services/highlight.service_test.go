@@ -0,0 +1,119 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"strings"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := map[string][]string{
+		"go mongo":                   {"go", "mongo"},
+		`"rest api" -draft`:          {"rest api"},
+		`-"old phrase" New!`:         {"new"},
+		`  spaced   words  `:         {"spaced", "words"},
+		`"unterminated phrase`:       {"unterminated phrase"},
+		`-`:                          nil,
+		`"" empty`:                   {"empty"},
+		`İstanbul ÇAĞRI`:             {"istanbul", "çağri"},
+		`punctuation, (parenthesis)`: {"punctuation", "parenthesis"},
+	}
+
+	for query, expected := range tests {
+		terms := searchTerms(query)
+		if strings.Join(terms, "|") != strings.Join(expected, "|") {
+			t.Errorf("%q: expected %q, got %q", query, expected, terms)
+		}
+	}
+}
+
+func TestHighlightText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		query    string
+		expected string
+	}{
+		{name: "word", text: "Go is fun", query: "go", expected: "<mark>Go</mark> is fun"},
+		{name: "word boundary", text: "Prune the runway, then run", query: "run", expected: "Prune the runway, then <mark>run</mark>"},
+		{name: "stemmed form", text: "Running and runs", query: "run", expected: "<mark>Running</mark> and <mark>runs</mark>"},
+		{name: "stemmed query", text: "I run daily", query: "running", expected: "I <mark>run</mark> daily"},
+		{name: "plural", text: "Notes about a note", query: "notes", expected: "<mark>Notes</mark> about a <mark>note</mark>"},
+		{name: "past tense", text: "It was noted", query: "note", expected: "It was <mark>noted</mark>"},
+		{name: "y ending", text: "Happy happiness", query: "happy", expected: "<mark>Happy</mark> happiness"},
+		{name: "phrase", text: "Build a rest api, rest and api", query: `"rest api"`, expected: "Build a <mark>rest api</mark>, rest and api"},
+		{name: "phrase across punctuation", text: "rest-api", query: `"rest api"`, expected: "<mark>rest-api</mark>"},
+		{name: "negated term", text: "draft and final", query: "final -draft", expected: "draft and <mark>final</mark>"},
+		{name: "unicode", text: "Çiçek GRÜNE Straße", query: "grüne", expected: "Çiçek <mark>GRÜNE</mark> Straße"},
+		{name: "escapes html", text: "<b>go</b> & more", query: "go", expected: "&lt;b&gt;<mark>go</mark>&lt;/b&gt; &amp; more"},
+		{name: "adjacent matches are merged", text: "mongo db", query: `mongo "mongo db"`, expected: "<mark>mongo db</mark>"},
+		{name: "no match", text: "nothing here", query: "else", expected: "nothing here"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			highlighted := highlightText([]rune(test.text), searchTerms(test.query))
+			if highlighted != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, highlighted)
+			}
+		})
+	}
+}
+
+func TestStemWord(t *testing.T) {
+	tests := map[string]string{
+		"run":       "run",
+		"runs":      "run",
+		"running":   "run",
+		"caresses":  "caress",
+		"ponies":    "poni",
+		"caress":    "caress",
+		"cats":      "cat",
+		"feed":      "feed",
+		"agreed":    "agree",
+		"plastered": "plaster",
+		"motoring":  "motor",
+		"sing":      "sing",
+		"conflated": "conflate",
+		"troubled":  "trouble",
+		"sized":     "size",
+		"hopping":   "hop",
+		"falling":   "fall",
+		"hissing":   "hiss",
+		"failing":   "fail",
+		"filing":    "file",
+		"happy":     "happi",
+		"sky":       "sky",
+		"go":        "go",
+	}
+
+	for word, expected := range tests {
+		if stem := string(stemWord([]rune(word))); stem != expected {
+			t.Errorf("%q: expected %q, got %q", word, expected, stem)
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	content := strings.Repeat("lorem ipsum dolor ", 20) + "the searched word " + strings.Repeat("sit amet ", 20)
+	note := &db.Note{Title: "Searched title", Content: content}
+
+	highlights := HighlightNote(note, "searched")
+	if highlights.Title != "<mark>Searched</mark> title" {
+		t.Fatalf("unexpected title %q", highlights.Title)
+	}
+	if !strings.HasPrefix(highlights.Content, "…") || !strings.HasSuffix(highlights.Content, "…") {
+		t.Fatalf("expected snippet to be cut at both ends, got %q", highlights.Content)
+	}
+	if !strings.Contains(highlights.Content, "<mark>searched</mark>") {
+		t.Fatalf("expected snippet around the match, got %q", highlights.Content)
+	}
+	if length := len([]rune(strings.NewReplacer("<mark>", "", "</mark>", "").Replace(highlights.Content))); length > noteSnippetLength+2 {
+		t.Fatalf("expected snippet of at most %d characters, got %d", noteSnippetLength, length)
+	}
+
+	short := HighlightNote(&db.Note{Title: "t", Content: "short searched text"}, "searched")
+	if short.Content != "short <mark>searched</mark> text" {
+		t.Fatalf("unexpected short content %q", short.Content)
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	loginDelayAfter       = 3                // failures before progressive delay starts
+	loginDelayMax         = 30 * time.Second // max progressive delay
+	loginAttemptPruneTime = time.Minute      // interval of deleting expired counters from memory
+)
+
+// loginAttemptStore keeps failed login counters, counters expire after ttl without new failures.
+// Fail increases the counter atomically and returns the new count, Release undoes one Fail
+type loginAttemptStore interface {
+	Get(key string) (failures int, last time.Time)
+	Fail(key string, ttl time.Duration) int
+	Release(key string)
+	Reset(key string)
+}
+
+type memoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*memoryAttempt
+}
+
+type memoryAttempt struct {
+	failures  int
+	last      time.Time
+	expiresAt time.Time
+}
+
+func (s *memoryAttemptStore) Get(key string) (int, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok || time.Now().After(attempt.expiresAt) {
+		return 0, time.Time{}
+	}
+
+	return attempt.failures, attempt.last
+}
+
+func (s *memoryAttemptStore) Fail(key string, ttl time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	attempt, ok := s.attempts[key]
+	if !ok || now.After(attempt.expiresAt) {
+		attempt = &memoryAttempt{}
+		s.attempts[key] = attempt
+	}
+
+	attempt.failures++
+	attempt.last = now
+	attempt.expiresAt = now.Add(ttl)
+	return attempt.failures
+}
+
+func (s *memoryAttemptStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		attempt.failures--
+		if attempt.failures <= 0 {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+func (s *memoryAttemptStore) Reset(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+}
+
+// prune deletes expired counters every interval, so the map doesn't grow with old emails and ips
+func (s *memoryAttemptStore) prune(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.deleteExpired(time.Now())
+	}
+}
+
+func (s *memoryAttemptStore) deleteExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, attempt := range s.attempts {
+		if now.After(attempt.expiresAt) {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+type redisAttemptStore struct{}
+
+func (s *redisAttemptStore) Get(key string) (int, time.Time) {
+	values, err := GetRedisDefaultClient().HGetAll(context.TODO(), key).Result()
+	if err != nil {
+		return 0, time.Time{}
+	}
+
+	failures, _ := strconv.Atoi(values["failures"])
+	last, _ := strconv.ParseInt(values["last"], 10, 64)
+	return failures, time.Unix(0, last)
+}
+
+func (s *redisAttemptStore) Fail(key string, ttl time.Duration) int {
+	ctx := context.TODO()
+	pipe := GetRedisDefaultClient().TxPipeline()
+	failures := pipe.HIncrBy(ctx, key, "failures", 1)
+	pipe.HSet(ctx, key, "last", time.Now().UnixNano())
+	pipe.Expire(ctx, key, ttl)
+	_, _ = pipe.Exec(ctx)
+
+	return int(failures.Val())
+}
+
+func (s *redisAttemptStore) Release(key string) {
+	ctx := context.TODO()
+	failures, err := GetRedisDefaultClient().HIncrBy(ctx, key, "failures", -1).Result()
+	if err == nil && failures <= 0 {
+		_ = GetRedisDefaultClient().Del(ctx, key).Err()
+	}
+}
+
+func (s *redisAttemptStore) Reset(key string) {
+	_ = GetRedisDefaultClient().Del(context.TODO(), key).Err()
+}
+
+var loginAttempts loginAttemptStore
+var loginAttemptsOnce sync.Once
+
+func getLoginAttemptStore() loginAttemptStore {
+	loginAttemptsOnce.Do(func() {
+		if Config.UseRedis {
+			loginAttempts = &redisAttemptStore{}
+			return
+		}
+
+		store := &memoryAttemptStore{attempts: map[string]*memoryAttempt{}}
+		go store.prune(loginAttemptPruneTime)
+		loginAttempts = store
+	})
+
+	return loginAttempts
+}
+
+func getLoginAccountKey(email string) string {
+	return "login:fail:account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func getLoginIpKey(ip string) string {
+	return "login:fail:ip:" + ip
+}
+
+// ReserveLoginAttempt counts the attempt as failed before credentials are checked, so parallel
+// requests cannot pass the limits together. It returns how long the client has to wait if the
+// attempt is rejected, successful attempts have to call ReleaseLoginAttempt
+func ReserveLoginAttempt(email string, ip string) (time.Duration, error) {
+	store := getLoginAttemptStore()
+	accountKey, ipKey := getLoginAccountKey(email), getLoginIpKey(ip)
+
+	if wait := loginAttemptWait(store, accountKey, ipKey); wait > 0 {
+		return wait, loginWaitError(wait)
+	}
+
+	accountFailures := store.Fail(accountKey, loginLockout())
+	ipFailures := store.Fail(ipKey, loginLockout())
+	if accountFailures > Config.LoginMaxAttempts || ipFailures > Config.LoginMaxIpAttempts {
+		// limits are reached by parallel attempts after the wait check
+		store.Release(accountKey)
+		store.Release(ipKey)
+		wait := loginAttemptWait(store, accountKey, ipKey)
+		if wait <= 0 {
+			wait = time.Second
+		}
+		return wait, loginWaitError(wait)
+	}
+
+	return 0, nil
+}
+
+// ReleaseLoginAttempt undoes the attempt reserved by ReserveLoginAttempt after a successful login,
+// failure counter of email is cleared
+func ReleaseLoginAttempt(email string, ip string) {
+	store := getLoginAttemptStore()
+	store.Reset(getLoginAccountKey(email))
+	store.Release(getLoginIpKey(ip))
+}
+
+// ResetLoginFailures clears failure counter of email after a successful login
+func ResetLoginFailures(email string) {
+	getLoginAttemptStore().Reset(getLoginAccountKey(email))
+}
+
+// loginAttemptWait returns how long the client has to wait before trying to log in again
+func loginAttemptWait(store loginAttemptStore, accountKey string, ipKey string) time.Duration {
+	accountFailures, accountLast := store.Get(accountKey)
+	ipFailures, ipLast := store.Get(ipKey)
+
+	// ip is only locked out without progressive delays, many users can share an ip
+	wait := loginWait(accountFailures, accountLast, Config.LoginMaxAttempts)
+	if ipFailures >= Config.LoginMaxIpAttempts {
+		if ipWait := time.Until(ipLast.Add(loginLockout())); ipWait > wait {
+			wait = ipWait
+		}
+	}
+
+	return wait
+}
+
+func loginWaitError(wait time.Duration) error {
+	seconds := int(wait.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return errors.New("too many failed login attempts, try again in " + strconv.Itoa(seconds) + " seconds")
+}
+
+// loginWait locks out after maxAttempts failures, delays progressively before that
+func loginWait(failures int, last time.Time, maxAttempts int) time.Duration {
+	if failures >= maxAttempts {
+		return time.Until(last.Add(loginLockout()))
+	}
+
+	if failures >= loginDelayAfter {
+		delay := loginDelayMax
+		if shift := failures - loginDelayAfter; shift < 5 {
+			delay = time.Second << shift
+		}
+		return time.Until(last.Add(delay))
+	}
+
+	return 0
+}
+
+func loginLockout() time.Duration {
+	return time.Duration(Config.LoginLockoutMinutes) * time.Minute
+}
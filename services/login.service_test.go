@@ -0,0 +1,120 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useTestLoginAttempts replaces the login attempt store with an empty memory store for the duration of the test
+func useTestLoginAttempts(t *testing.T) *memoryAttemptStore {
+	t.Helper()
+
+	config := useTestConfig(t)
+	config.LoginMaxAttempts = 5
+	config.LoginMaxIpAttempts = 100
+	config.LoginLockoutMinutes = 15
+
+	loginAttemptsOnce.Do(func() {})
+	previous := loginAttempts
+	store := &memoryAttemptStore{attempts: map[string]*memoryAttempt{}}
+	loginAttempts = store
+	t.Cleanup(func() {
+		loginAttempts = previous
+	})
+
+	return store
+}
+
+func TestReserveLoginAttemptConcurrent(t *testing.T) {
+	useTestLoginAttempts(t)
+
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ReserveLoginAttempt("concurrent@example.com", "127.0.0.1"); err == nil {
+				reserved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count := reserved.Load(); count < 1 || count > int32(Config.LoginMaxAttempts) {
+		t.Fatalf("expected at most %d of concurrent attempts to be reserved, got %d", Config.LoginMaxAttempts, count)
+	}
+
+	failures, _ := getLoginAttemptStore().Get(getLoginAccountKey("concurrent@example.com"))
+	if failures != int(reserved.Load()) {
+		t.Fatalf("expected rejected attempts to be released, got %d failures for %d reserved", failures, reserved.Load())
+	}
+}
+
+func TestReserveLoginAttemptLockout(t *testing.T) {
+	useTestLoginAttempts(t)
+	Config.LoginMaxAttempts = 2
+
+	for i := 0; i < 2; i++ {
+		if _, err := ReserveLoginAttempt("Locked@example.com ", "127.0.0.1"); err != nil {
+			t.Fatalf("expected attempt %d to be reserved, got %v", i+1, err)
+		}
+	}
+
+	wait, err := ReserveLoginAttempt("locked@example.com", "127.0.0.2")
+	if err == nil {
+		t.Fatal("expected email to be locked out")
+	}
+	if wait < 14*time.Minute || wait > 15*time.Minute {
+		t.Fatalf("expected lockout of LOGIN_LOCKOUT_MINUTES, got %v", wait)
+	}
+
+	if _, err = ReserveLoginAttempt("other@example.com", "127.0.0.1"); err != nil {
+		t.Fatalf("expected other email from the same ip to be reserved, got %v", err)
+	}
+}
+
+func TestReleaseLoginAttempt(t *testing.T) {
+	store := useTestLoginAttempts(t)
+
+	ipKey := getLoginIpKey("127.0.0.1")
+	store.Fail(ipKey, time.Minute)
+	if _, err := ReserveLoginAttempt("success@example.com", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	ReleaseLoginAttempt("success@example.com", "127.0.0.1")
+
+	if failures, _ := store.Get(getLoginAccountKey("success@example.com")); failures != 0 {
+		t.Fatalf("expected failures of email to be cleared, got %d", failures)
+	}
+	if failures, _ := store.Get(ipKey); failures != 1 {
+		t.Fatalf("expected only the reserved attempt of ip to be released, got %d failures", failures)
+	}
+}
+
+func TestMemoryAttemptStoreExpiry(t *testing.T) {
+	store := &memoryAttemptStore{attempts: map[string]*memoryAttempt{}}
+
+	store.Fail("expired", time.Millisecond)
+	store.Fail("expired", time.Millisecond)
+	store.Fail("kept", time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	if failures, _ := store.Get("expired"); failures != 0 {
+		t.Fatalf("expected expired counter to be ignored, got %d", failures)
+	}
+	if failures := store.Fail("expired", time.Millisecond); failures != 1 {
+		t.Fatalf("expected expired counter to start again, got %d", failures)
+	}
+
+	store.deleteExpired(time.Now().Add(time.Second))
+	if _, ok := store.attempts["expired"]; ok {
+		t.Fatal("expected expired counter to be deleted")
+	}
+	if _, ok := store.attempts["kept"]; !ok {
+		t.Fatal("expected counter which is not expired to be kept")
+	}
+}
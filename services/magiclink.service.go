@@ -0,0 +1,78 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+const magicLinkResendInterval = time.Minute
+
+// SendMagicLinkMail mails a single use login link, unknown emails and disabled users are ignored silently
+func SendMagicLinkMail(email string) {
+	user, err := FindUserByEmail(email)
+	if err != nil || user.Disabled {
+		return
+	}
+
+	// a link is sent at most once in resend interval, so the endpoint cannot flood a mailbox,
+	// concurrent requests cannot both pass it, only one of them updates the send time
+	now := time.Now()
+	updateResult, err := mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{
+			field.ID: user.ID,
+			"$or": bson.A{
+				bson.M{"magic_link_sent_at": bson.M{"$exists": false}},
+				bson.M{"magic_link_sent_at": bson.M{"$lte": now.Add(-magicLinkResendInterval)}},
+			},
+		},
+		bson.M{"$set": bson.M{"magic_link_sent_at": now}},
+	)
+	if err != nil || updateResult.MatchedCount == 0 {
+		return
+	}
+
+	_ = BlacklistUserTokensByType(user.ID, db.TokenTypeMagicLink)
+
+	expiresAt := now.Add(time.Duration(Config.MagicLinkExpirationMinutes) * time.Minute)
+	token, err := CreateOneTimeToken(user.ID, db.TokenTypeMagicLink, expiresAt)
+	if err != nil {
+		return
+	}
+
+	body := "Hi " + user.Name + ",\n\n" +
+		"You can log in by opening the link below:\n\n" +
+		Config.AppUrl + "/magic-link?token=" + token.Token + "\n\n" +
+		"The link can be used once and expires at " + expiresAt.Format("2006-01-02 15:04:05") + ". " +
+		"If you didn't request a login link, you can ignore this email."
+
+	_ = SendMail(user.Email, "Your login link", body)
+}
+
+// UseMagicLink uses a login link token and returns its user
+func UseMagicLink(token string) (*db.User, error) {
+	tokenModel, err := UseOneTimeToken(token, db.TokenTypeMagicLink)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := FindUserById(tokenModel.User)
+	if err != nil {
+		return nil, err
+	}
+
+	// login link proves the ownership of the email
+	if !user.MailVerified {
+		user.MailVerified = true
+		_, _ = mgm.Coll(user).UpdateOne(
+			mgm.Ctx(),
+			bson.M{field.ID: user.ID},
+			bson.M{"$set": bson.M{"mail_verified": true}},
+		)
+	}
+
+	return user, nil
+}
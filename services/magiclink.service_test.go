@@ -0,0 +1,53 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSendMagicLinkMailConcurrent(t *testing.T) {
+	config := useTestDatabase(t)
+	config.MagicLinkExpirationMinutes = 15
+	mails := useTestMailer(t)
+
+	user, err := CreateUser("Test", "magic@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			SendMagicLinkMail(user.Email)
+		}()
+	}
+	wg.Wait()
+
+	if mails.count() != 1 {
+		t.Fatalf("expected only one of concurrent requests to send a link, got %d", mails.count())
+	}
+
+	// a new link is sent after the resend interval
+	_, err = mgm.Coll(user).UpdateByID(mgm.Ctx(), user.ID, bson.M{"$set": bson.M{"magic_link_sent_at": time.Now().Add(-magicLinkResendInterval)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SendMagicLinkMail(user.Email)
+	if mails.count() != 2 {
+		t.Fatalf("expected a new link after resend interval, got %d mails", mails.count())
+	}
+
+	count, err := mgm.Coll(&db.Token{}).CountDocuments(mgm.Ctx(), bson.M{"user": user.ID, "type": db.TokenTypeMagicLink, "blacklisted": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected only the last link to be usable, got %d", count)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverLog  = "log"
+)
+
+// Mailer sends plain text emails
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// SMTPMailer sends emails with an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to string, subject string, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	message := "From: " + m.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=\"utf-8\"\r\n" +
+		"\r\n" + body
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, []byte(message))
+}
+
+// LogMailer writes emails to a log file instead of sending, for dev and tests
+type LogMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (m *LogMailer) Send(to string, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_ = os.MkdirAll(path.Dir(m.Path), 0770)
+	file, err := os.OpenFile(m.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0660)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "--- %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), to, subject, body)
+	return err
+}
+
+var mailer Mailer
+var mailerOnce sync.Once
+
+func GetMailer() Mailer {
+	mailerOnce.Do(func() {
+		if Config.MailDriver == MailDriverSMTP {
+			mailer = &SMTPMailer{
+				Host:     Config.SMTPHost,
+				Port:     Config.SMTPPort,
+				Username: Config.SMTPUsername,
+				Password: Config.SMTPPassword,
+				From:     Config.MailFrom,
+			}
+			return
+		}
+
+		mailer = &LogMailer{Path: Config.MailLogPath}
+	})
+
+	return mailer
+}
+
+// SetMailer replaces the mailer, useful for tests
+func SetMailer(m Mailer) {
+	mailerOnce.Do(func() {})
+	mailer = m
+}
+
+// SendMail sends an email with configured mailer and logs failures
+func SendMail(to string, subject string, body string) error {
+	err := GetMailer().Send(to, subject, body)
+	if err != nil {
+		log.Printf("cannot send mail to %s: %s\n", to, err)
+	}
+
+	return err
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod            = 30 // seconds
+	totpDigits            = 6
+	totpSkew              = 1 // accepted periods before and after now
+	mfaRecoveryCodeCount  = 10
+	mfaChallengeExpiresIn = 5 * time.Minute
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// SetupTOTP creates a new secret for the user, MFA is enabled after it is confirmed with a code
+func SetupTOTP(user *db.User) (string, error) {
+	if user.MFAEnabled {
+		return "", errors.New("mfa is already enabled")
+	}
+
+	secretBytes := make([]byte, 20)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", errors.New("cannot create mfa secret")
+	}
+	secret := base32NoPadding.EncodeToString(secretBytes)
+
+	_, err := mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID},
+		bson.M{"$set": bson.M{"mfa_secret": secret}},
+	)
+	if err != nil {
+		return "", errors.New("cannot save mfa secret")
+	}
+
+	user.MFASecret = secret
+	return totpAuthURI(secret, user.Email), nil
+}
+
+// ConfirmTOTP enables MFA if the code is valid for the pending secret, returns plain recovery codes once
+func ConfirmTOTP(user *db.User, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, errors.New("mfa is already enabled")
+	}
+
+	if user.MFASecret == "" {
+		return nil, errors.New("mfa setup is not started")
+	}
+
+	step, ok := validateTOTP(user.MFASecret, code, 0)
+	if !ok {
+		return nil, errors.New("not valid code")
+	}
+
+	recoveryCodes, hashedCodes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID},
+		bson.M{"$set": bson.M{
+			"mfa_enabled":        true,
+			"mfa_last_step":      step,
+			"mfa_recovery_codes": hashedCodes,
+		}},
+	)
+	if err != nil {
+		return nil, errors.New("cannot enable mfa")
+	}
+
+	user.MFAEnabled = true
+	return recoveryCodes, nil
+}
+
+// DisableMFA removes MFA secret and recovery codes of the user
+func DisableMFA(user *db.User) error {
+	if !user.MFAEnabled {
+		return errors.New("mfa is not enabled")
+	}
+
+	_, err := mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID},
+		bson.M{
+			"$set":   bson.M{"mfa_enabled": false},
+			"$unset": bson.M{"mfa_secret": "", "mfa_last_step": "", "mfa_recovery_codes": ""},
+		},
+	)
+	if err != nil {
+		return errors.New("cannot disable mfa")
+	}
+
+	user.MFAEnabled = false
+	user.MFASecret = ""
+	return nil
+}
+
+// CreateMFAChallenge creates a short-lived token which is exchanged with access tokens after MFA code check
+func CreateMFAChallenge(user *db.User) (*db.Token, error) {
+	return CreateOneTimeToken(user.ID, db.TokenTypeMFAChallenge, time.Now().Add(mfaChallengeExpiresIn))
+}
+
+// FindMFAChallengeUser finds the user of a not expired challenge token
+func FindMFAChallengeUser(token string) (*db.User, error) {
+	challenge, err := FindOneTimeToken(token, db.TokenTypeMFAChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := FindUserById(challenge.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.MFAEnabled {
+		return nil, errors.New("mfa is not enabled")
+	}
+
+	return user, nil
+}
+
+// CheckMFACode checks a TOTP or recovery code of the user, codes are used like in login
+func CheckMFACode(user *db.User, code string) error {
+	if !user.MFAEnabled {
+		return errors.New("mfa is not enabled")
+	}
+
+	return verifyMFACode(user, code)
+}
+
+// CompleteMFAChallenge checks the code with TOTP or a recovery code and uses the challenge token
+func CompleteMFAChallenge(user *db.User, token string, code string) error {
+	err := verifyMFACode(user, code)
+	if err != nil {
+		return err
+	}
+
+	_, err = UseOneTimeToken(token, db.TokenTypeMFAChallenge)
+	return err
+}
+
+// verifyMFACode accepts a TOTP code only once, or a recovery code which is removed after use
+func verifyMFACode(user *db.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := validateTOTP(user.MFASecret, code, user.MFALastStep); ok {
+		// last step is updated conditionally, so the same code cannot be used twice
+		updateResult, err := mgm.Coll(user).UpdateOne(
+			mgm.Ctx(),
+			bson.M{field.ID: user.ID, "mfa_last_step": bson.M{"$lt": step}},
+			bson.M{"$set": bson.M{"mfa_last_step": step}},
+		)
+		if err != nil || updateResult.ModifiedCount <= 0 {
+			return errors.New("not valid code")
+		}
+
+		user.MFALastStep = step
+		return nil
+	}
+
+	hashedCode := hashToken(normalizeRecoveryCode(code))
+	updateResult, err := mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID, "mfa_recovery_codes": hashedCode},
+		bson.M{"$pull": bson.M{"mfa_recovery_codes": hashedCode}},
+	)
+	if err != nil || updateResult.ModifiedCount <= 0 {
+		return errors.New("not valid code")
+	}
+
+	return nil
+}
+
+func totpAuthURI(secret string, email string) string {
+	issuer := Config.MFAIssuer
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + email)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// validateTOTP checks code around current time step, steps not after lastStep are rejected
+func validateTOTP(secret string, code string, lastStep int64) (int64, bool) {
+	return validateTOTPAt(secret, code, lastStep, time.Now())
+}
+
+func validateTOTPAt(secret string, code string, lastStep int64, now time.Time) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	currentStep := now.Unix() / totpPeriod
+	for step := currentStep - totpSkew; step <= currentStep+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpCode generates RFC 6238 code of a time step
+func totpCode(key []byte, step int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashedCodes := make([]string, 0, mfaRecoveryCodeCount)
+
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		randomBytes := make([]byte, 10)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, nil, errors.New("cannot create recovery codes")
+		}
+
+		code := strings.ToLower(base32NoPadding.EncodeToString(randomBytes))
+		codes = append(codes, code[:8]+"-"+code[8:])
+		hashedCodes = append(hashedCodes, hashToken(code))
+	}
+
+	return codes, hashedCodes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed of RFC 6238 appendix B test vectors
+var rfc6238Secret = base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// 6 digit codes are the last digits of 8 digit codes in the RFC
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	key, err := base32NoPadding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		if code := totpCode(key, test.unix/totpPeriod); code != test.code {
+			t.Errorf("T=%d: expected %s, got %s", test.unix, test.code, code)
+		}
+
+		step, ok := validateTOTPAt(rfc6238Secret, test.code, 0, time.Unix(test.unix, 0))
+		if !ok || step != test.unix/totpPeriod {
+			t.Errorf("T=%d: expected code to be valid at step %d, got %d %v", test.unix, test.unix/totpPeriod, step, ok)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// code of T=1111111109 is at step 37037036
+	const code = "081804"
+	const step = int64(37037036)
+
+	tests := []struct {
+		name  string
+		unix  int64
+		valid bool
+	}{
+		{name: "same step", unix: step * totpPeriod, valid: true},
+		{name: "one step later", unix: (step + 1) * totpPeriod, valid: true},
+		{name: "one step earlier", unix: (step - 1) * totpPeriod, valid: true},
+		{name: "two steps later", unix: (step + 2) * totpPeriod, valid: false},
+		{name: "two steps earlier", unix: (step - 2) * totpPeriod, valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matched, ok := validateTOTPAt(rfc6238Secret, code, 0, time.Unix(test.unix, 0))
+			if ok != test.valid {
+				t.Fatalf("expected valid %v, got %v", test.valid, ok)
+			}
+			if ok && matched != step {
+				t.Fatalf("expected step %d, got %d", step, matched)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step, ok := validateTOTPAt(rfc6238Secret, "081804", 0, now)
+	if !ok {
+		t.Fatal("expected code to be valid")
+	}
+
+	if _, ok = validateTOTPAt(rfc6238Secret, "081804", step, now); ok {
+		t.Fatal("expected used code to be rejected")
+	}
+	if _, ok = validateTOTPAt(rfc6238Secret, "081804", step, now.Add(totpPeriod*time.Second)); ok {
+		t.Fatal("expected used code to be rejected in the next step")
+	}
+
+	// a later code is still accepted after the last used step
+	if _, ok = validateTOTPAt(rfc6238Secret, "050471", step, time.Unix(1111111111, 0)); !ok {
+		t.Fatal("expected code of a later step to be valid")
+	}
+}
+
+func TestValidateTOTPMalformed(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok := validateTOTPAt(rfc6238Secret, code, 0, now); ok {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+
+	if _, ok := validateTOTPAt("not base32!", "287082", 0, now); ok {
+		t.Error("expected malformed secret to be rejected")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashedCodes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != mfaRecoveryCodeCount || len(hashedCodes) != mfaRecoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d and %d hashes", mfaRecoveryCodeCount, len(codes), len(hashedCodes))
+	}
+
+	format := regexp.MustCompile("^[a-z2-7]{8}-[a-z2-7]{8}$")
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("unexpected recovery code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate recovery code %q", code)
+		}
+		seen[code] = true
+
+		if hashedCodes[i] == code || hashedCodes[i] != hashToken(normalizeRecoveryCode(code)) {
+			t.Errorf("hash of %q does not match its normalized code", code)
+		}
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := map[string]string{
+		"abcdefgh-ijklmnop": "abcdefghijklmnop",
+		"ABCDEFGH-IJKLMNOP": "abcdefghijklmnop",
+		"abcdefghijklmnop":  "abcdefghijklmnop",
+	}
+
+	for code, expected := range tests {
+		if normalized := normalizeRecoveryCode(code); normalized != expected {
+			t.Errorf("%q: expected %q, got %q", code, expected, normalized)
+		}
+	}
+}
+
+func TestCheckMFACodeUsesCodesOnce(t *testing.T) {
+	useTestDatabase(t)
+
+	user, err := CreateExternalUser("Test", "mfa@example.com", db.UserIdentity{Provider: "test", Subject: "mfa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = SetupTOTP(user); err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := base32NoPadding.DecodeString(user.MFASecret)
+	recoveryCodes, err := ConfirmTOTP(user, totpCode(key, time.Now().Unix()/totpPeriod-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, _ = FindUserById(user.ID)
+
+	code := totpCode(key, time.Now().Unix()/totpPeriod)
+	if err = CheckMFACode(user, code); err != nil {
+		t.Fatalf("expected current code to be valid: %v", err)
+	}
+	if err = CheckMFACode(user, code); err == nil {
+		t.Fatal("expected replayed code to be rejected")
+	}
+
+	if err = CheckMFACode(user, recoveryCodes[0]); err != nil {
+		t.Fatalf("expected recovery code to be valid: %v", err)
+	}
+	if err = CheckMFACode(user, recoveryCodes[0]); err == nil {
+		t.Fatal("expected used recovery code to be rejected")
+	}
+}
@@ -8,12 +8,21 @@ import (
 	"github.com/kamva/mgm/v3/field"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
 )
 
-// CreateNote create new note record
-func CreateNote(userId primitive.ObjectID, title string, content string) (*db.Note, error) {
-	note := db.NewNote(userId, title, content)
+// CreateNote create new note record, notebookId is nil for notes out of notebooks
+func CreateNote(userId primitive.ObjectID, title string, content string, tags []string, notebookId *primitive.ObjectID) (*db.Note, error) {
+	if notebookId != nil {
+		if _, err := GetNotebookById(userId, *notebookId); err != nil {
+			return nil, err
+		}
+	}
+
+	note := db.NewNote(userId, title, content, tags, notebookId)
 	err := mgm.Coll(note).Create(note)
 	if err != nil {
 		return nil, errors.New("cannot create new note")
@@ -22,17 +31,26 @@ func CreateNote(userId primitive.ObjectID, title string, content string) (*db.No
 	return note, nil
 }
 
-// GetNotes get paginated note list
-func GetNotes(userId primitive.ObjectID, page int, limit int) ([]db.Note, error) {
+// GetNotes get paginated note list, notes are filtered by any of the tags or all of them with matchAll
+func GetNotes(userId primitive.ObjectID, tags []string, matchAll bool, page int, limit int) ([]db.Note, error) {
 	var notes []db.Note
 
 	findOptions := options.Find().
 		SetSkip(int64(page * limit)).
 		SetLimit(int64(limit + 1))
 
+	filter := bson.M{"author": userId, "deleted_at": nil}
+	if tags = db.NormalizeTags(tags); len(tags) > 0 {
+		operator := "$in"
+		if matchAll {
+			operator = "$all"
+		}
+		filter["tags"] = bson.M{operator: tags}
+	}
+
 	err := mgm.Coll(&db.Note{}).SimpleFind(
 		&notes,
-		bson.M{"author": userId},
+		filter,
 		findOptions,
 	)
 
@@ -43,9 +61,76 @@ func GetNotes(userId primitive.ObjectID, page int, limit int) ([]db.Note, error)
 	return notes, nil
 }
 
+// SearchNotes finds notes with a text search query sorted by relevance, query supports "phrases" and -negations
+func SearchNotes(userId primitive.ObjectID, query string, page int, limit int) ([]db.NoteSearchResult, error) {
+	var notes []db.NoteSearchResult
+	score := bson.M{"$meta": "textScore"}
+
+	findOptions := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "created_at", Value: -1}}).
+		SetSkip(int64(page * limit)).
+		SetLimit(int64(limit + 1))
+
+	err := mgm.Coll(&db.Note{}).SimpleFind(
+		&notes,
+		bson.M{"author": userId, "deleted_at": nil, "$text": bson.M{"$search": query}},
+		findOptions,
+	)
+
+	if err != nil {
+		return nil, errors.New("cannot search notes")
+	}
+
+	for i := range notes {
+		notes[i].Highlights = HighlightNote(&notes[i].Note, query)
+	}
+
+	return notes, nil
+}
+
+// EnsureNoteIndexes creates indexes of tag, notebook and trash filters and the text index of note search, title matches weigh more than content.
+// Text index is prefixed with author, so searches scan only notes of the user.
+func EnsureNoteIndexes() {
+	_, err := mgm.Coll(&db.Note{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "author", Value: 1}, {Key: "title", Value: "text"}, {Key: "content", Value: "text"}},
+			Options: options.Index().
+				SetName("notes_author_text").
+				SetWeights(bson.M{"title": 3, "content": 1}),
+		},
+		{
+			Keys:    bson.D{{Key: "author", Value: 1}, {Key: "tags", Value: 1}},
+			Options: options.Index().SetName("notes_author_tags"),
+		},
+		{
+			Keys:    bson.D{{Key: "author", Value: 1}, {Key: "notebook_id", Value: 1}},
+			Options: options.Index().SetName("notes_author_notebook"),
+		},
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("notes_deleted_at").SetSparse(true),
+		},
+	})
+
+	if err != nil {
+		log.Println("cannot create note indexes:", err)
+	}
+}
+
+// CountNotes counts notes of a user
+func CountNotes(userId primitive.ObjectID) (int64, error) {
+	count, err := mgm.Coll(&db.Note{}).CountDocuments(mgm.Ctx(), bson.M{"author": userId, "deleted_at": nil})
+	if err != nil {
+		return 0, errors.New("cannot count notes")
+	}
+
+	return count, nil
+}
+
 func GetNoteById(userId primitive.ObjectID, noteId primitive.ObjectID) (*db.Note, error) {
 	note := &db.Note{}
-	err := mgm.Coll(note).First(bson.M{field.ID: noteId, "author": userId}, note)
+	err := mgm.Coll(note).First(bson.M{field.ID: noteId, "author": userId, "deleted_at": nil}, note)
 	if err != nil {
 		return nil, errors.New("cannot find note")
 	}
@@ -57,7 +142,7 @@ func GetNoteById(userId primitive.ObjectID, noteId primitive.ObjectID) (*db.Note
 func UpdateNote(userId primitive.ObjectID, noteId primitive.ObjectID, request *models.NoteRequest) error {
 	note := &db.Note{}
 	err := mgm.Coll(note).FindByID(noteId, note)
-	if err != nil {
+	if err != nil || note.DeletedAt != nil {
 		return errors.New("cannot find note")
 	}
 
@@ -65,24 +150,93 @@ func UpdateNote(userId primitive.ObjectID, noteId primitive.ObjectID, request *m
 		return errors.New("you cannot update this note")
 	}
 
+	previous := *note
 	note.Title = request.Title
 	note.Content = request.Content
-	err = mgm.Coll(note).Update(note)
-
-	if err != nil {
-		return errors.New("cannot update")
+	if request.Tags != nil {
+		note.Tags = db.NormalizeTags(request.Tags)
+	}
+	if request.NotebookId != "" {
+		notebookId, _ := primitive.ObjectIDFromHex(request.NotebookId)
+		if _, err = GetNotebookById(userId, notebookId); err != nil {
+			return err
+		}
+		note.Notebook = &notebookId
 	}
 
-	return nil
+	return updateNoteWithVersion(&previous, note)
 }
 
-// DeleteNote delete a note with id
+// DeleteNote moves a note to trash, it is deleted permanently after TRASH_RETENTION_DAYS
 func DeleteNote(userId primitive.ObjectID, noteId primitive.ObjectID) error {
-	deleteResult, err := mgm.Coll(&db.Note{}).DeleteOne(mgm.Ctx(), bson.M{field.ID: noteId, "author": userId})
+	updateResult, err := mgm.Coll(&db.Note{}).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: noteId, "author": userId, "deleted_at": nil},
+		bson.M{"$set": bson.M{"deleted_at": time.Now()}},
+	)
 
-	if err != nil || deleteResult.DeletedCount <= 0 {
+	if err != nil || updateResult.ModifiedCount <= 0 {
 		return errors.New("cannot delete note")
 	}
 
+	DeleteNoteFromCache(userId, noteId)
+
 	return nil
 }
+
+// deleteNotes deletes notes of the filter with their versions
+func deleteNotes(filter bson.M) (int64, error) {
+	ids, err := findNoteIds(filter)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	// the filter is checked again, notes can be restored after they are found
+	deleteResult, err := mgm.Coll(&db.Note{}).DeleteMany(mgm.Ctx(), bson.M{"$and": bson.A{filter, bson.M{field.ID: bson.M{"$in": ids}}}})
+	if err != nil {
+		return 0, err
+	}
+
+	if deleteResult.DeletedCount < int64(len(ids)) {
+		kept, err := findNoteIds(bson.M{field.ID: bson.M{"$in": ids}})
+		if err != nil {
+			return deleteResult.DeletedCount, nil
+		}
+		ids = excludeIds(ids, kept)
+	}
+
+	_, _ = mgm.Coll(&db.NoteVersion{}).DeleteMany(mgm.Ctx(), bson.M{"note": bson.M{"$in": ids}})
+
+	return deleteResult.DeletedCount, nil
+}
+
+func findNoteIds(filter bson.M) ([]primitive.ObjectID, error) {
+	var notes []db.Note
+	err := mgm.Coll(&db.Note{}).SimpleFind(&notes, filter, options.Find().SetProjection(bson.M{field.ID: 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+
+	return ids, nil
+}
+
+func excludeIds(ids []primitive.ObjectID, excluded []primitive.ObjectID) []primitive.ObjectID {
+	excludedSet := make(map[primitive.ObjectID]bool, len(excluded))
+	for _, id := range excluded {
+		excludedSet[id] = true
+	}
+
+	remaining := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if !excludedSet[id] {
+			remaining = append(remaining, id)
+		}
+	}
+
+	return remaining
+}
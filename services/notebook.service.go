@@ -0,0 +1,349 @@
+package services
+
+import (
+	"errors"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"strconv"
+	"time"
+)
+
+// CreateNotebook creates a notebook under the parent, parentId is nil for top level notebooks
+func CreateNotebook(userId primitive.ObjectID, name string, parentId *primitive.ObjectID) (*db.Notebook, error) {
+	var parent *db.Notebook
+	if parentId != nil {
+		var err error
+		parent, err = GetNotebookById(userId, *parentId)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.Depth() >= Config.NotebookMaxDepth {
+			return nil, errNotebookTooDeep()
+		}
+	}
+
+	notebook := db.NewNotebook(userId, name, parent)
+	err := mgm.Coll(notebook).Create(notebook)
+	if err != nil {
+		return nil, errors.New("cannot create new notebook")
+	}
+
+	return notebook, nil
+}
+
+// GetNotebooks returns all notebooks of the user sorted by name, clients build the tree with parent_id
+func GetNotebooks(userId primitive.ObjectID) ([]db.Notebook, error) {
+	notebooks := []db.Notebook{}
+
+	err := mgm.Coll(&db.Notebook{}).SimpleFind(
+		&notebooks,
+		bson.M{"author": userId},
+		options.Find().SetSort(bson.M{"name": 1}),
+	)
+
+	if err != nil {
+		return nil, errors.New("cannot find notebooks")
+	}
+
+	return notebooks, nil
+}
+
+func GetNotebookById(userId primitive.ObjectID, notebookId primitive.ObjectID) (*db.Notebook, error) {
+	notebook := &db.Notebook{}
+	err := mgm.Coll(notebook).First(bson.M{field.ID: notebookId, "author": userId}, notebook)
+	if err != nil {
+		return nil, errors.New("cannot find notebook")
+	}
+
+	return notebook, nil
+}
+
+// UpdateNotebook renames the notebook and moves it with its sub notebooks under the parent
+func UpdateNotebook(userId primitive.ObjectID, notebookId primitive.ObjectID, name string, parentId *primitive.ObjectID) (*db.Notebook, error) {
+	notebook, err := GetNotebookById(userId, notebookId)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors := []primitive.ObjectID{}
+	if parentId != nil {
+		parent, err := GetNotebookById(userId, *parentId)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.ID == notebook.ID || containsObjectId(parent.Ancestors, notebook.ID) {
+			return nil, errors.New("notebook cannot be moved into itself or its sub notebooks")
+		}
+		ancestors = parent.Path()
+	}
+
+	moved := !sameObjectId(notebook.Parent, parentId)
+	if moved {
+		height, err := getNotebookHeight(notebook)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ancestors)+height > Config.NotebookMaxDepth {
+			return nil, errNotebookTooDeep()
+		}
+	}
+
+	oldAncestorCount := len(notebook.Ancestors)
+	notebook.Name = name
+	notebook.Parent = parentId
+	notebook.Ancestors = ancestors
+
+	err = mgm.Coll(notebook).Update(notebook)
+	if err != nil {
+		return nil, errors.New("cannot update notebook")
+	}
+
+	if moved {
+		// sub notebooks keep their path below the notebook
+		_, err = mgm.Coll(notebook).UpdateMany(
+			mgm.Ctx(),
+			bson.M{"author": userId, "ancestors": notebook.ID},
+			bson.A{bson.M{"$set": bson.M{
+				"ancestors": bson.M{"$concatArrays": bson.A{
+					notebook.Path(),
+					bson.M{"$slice": bson.A{"$ancestors", oldAncestorCount + 1, Config.NotebookMaxDepth}},
+				}},
+				"updated_at": time.Now(),
+			}}},
+		)
+		if err != nil {
+			return nil, errors.New("cannot move sub notebooks")
+		}
+	}
+
+	return notebook, nil
+}
+
+// DeleteNotebook deletes the notebook, with cascade its sub notebooks are deleted and notes are moved to trash,
+// otherwise they are moved to its parent
+func DeleteNotebook(userId primitive.ObjectID, notebookId primitive.ObjectID, cascade bool) error {
+	notebook, err := GetNotebookById(userId, notebookId)
+	if err != nil {
+		return err
+	}
+
+	if cascade {
+		ids, err := getNotebookSubtreeIds(notebook)
+		if err != nil {
+			return err
+		}
+
+		// notes are moved to trash, they are moved out of notebooks when they are restored
+		deletedAt := time.Now()
+		_, err = mgm.Coll(&db.Note{}).UpdateMany(
+			mgm.Ctx(),
+			bson.M{"author": userId, "notebook_id": bson.M{"$in": ids}, "deleted_at": nil},
+			bson.M{"$set": bson.M{"deleted_at": deletedAt}},
+		)
+		if err != nil {
+			return errors.New("cannot delete notes of notebook")
+		}
+
+		// trashed notes are found by their delete time, notes trashed before are not cached
+		noteIds, _ := findNoteIds(bson.M{"author": userId, "notebook_id": bson.M{"$in": ids}, "deleted_at": deletedAt})
+		DeleteNotesFromCache(userId, noteIds)
+
+		_, err = mgm.Coll(notebook).DeleteMany(mgm.Ctx(), bson.M{"author": userId, field.ID: bson.M{"$in": ids}})
+		if err != nil {
+			return errors.New("cannot delete notebook")
+		}
+
+		return nil
+	}
+
+	noteIds, err := findNoteIds(bson.M{"author": userId, "notebook_id": notebook.ID})
+	if err != nil {
+		return errors.New("cannot move notes of notebook")
+	}
+
+	_, err = mgm.Coll(&db.Note{}).UpdateMany(
+		mgm.Ctx(),
+		bson.M{"author": userId, "notebook_id": notebook.ID},
+		noteNotebookUpdate(notebook.Parent),
+	)
+	if err != nil {
+		return errors.New("cannot move notes of notebook")
+	}
+	DeleteNotesFromCache(userId, noteIds)
+
+	_, err = mgm.Coll(notebook).UpdateMany(
+		mgm.Ctx(),
+		bson.M{"author": userId, "parent": notebook.ID},
+		bson.M{"$set": bson.M{"parent": notebook.Parent}},
+	)
+	if err == nil {
+		_, err = mgm.Coll(notebook).UpdateMany(
+			mgm.Ctx(),
+			bson.M{"author": userId, "ancestors": notebook.ID},
+			bson.M{"$pull": bson.M{"ancestors": notebook.ID}, "$set": bson.M{"updated_at": time.Now()}},
+		)
+	}
+	if err != nil {
+		return errors.New("cannot move sub notebooks")
+	}
+
+	err = mgm.Coll(notebook).Delete(notebook)
+	if err != nil {
+		return errors.New("cannot delete notebook")
+	}
+
+	return nil
+}
+
+// GetNotebookNotes get paginated notes of the notebook and its sub notebooks
+func GetNotebookNotes(userId primitive.ObjectID, notebookId primitive.ObjectID, page int, limit int) ([]db.Note, error) {
+	notebook, err := GetNotebookById(userId, notebookId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := getNotebookSubtreeIds(notebook)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []db.Note
+
+	findOptions := options.Find().
+		SetSkip(int64(page * limit)).
+		SetLimit(int64(limit + 1))
+
+	err = mgm.Coll(&db.Note{}).SimpleFind(
+		&notes,
+		bson.M{"author": userId, "notebook_id": bson.M{"$in": ids}, "deleted_at": nil},
+		findOptions,
+	)
+
+	if err != nil {
+		return nil, errors.New("cannot find notes")
+	}
+
+	return notes, nil
+}
+
+// MoveNote moves a note to the notebook, notebookId is nil to move it out of notebooks
+func MoveNote(userId primitive.ObjectID, noteId primitive.ObjectID, notebookId *primitive.ObjectID) error {
+	if notebookId != nil {
+		if _, err := GetNotebookById(userId, *notebookId); err != nil {
+			return err
+		}
+	}
+
+	result, err := mgm.Coll(&db.Note{}).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: noteId, "author": userId, "deleted_at": nil},
+		noteNotebookUpdate(notebookId),
+	)
+
+	if err != nil || result.MatchedCount == 0 {
+		return errors.New("cannot move note")
+	}
+
+	DeleteNoteFromCache(userId, noteId)
+
+	return nil
+}
+
+// EnsureNotebookIndexes creates indexes of notebook trees
+func EnsureNotebookIndexes() {
+	_, err := mgm.Coll(&db.Notebook{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "author", Value: 1}, {Key: "ancestors", Value: 1}},
+			Options: options.Index().SetName("notebooks_author_ancestors"),
+		},
+		{
+			Keys:    bson.D{{Key: "author", Value: 1}, {Key: "parent", Value: 1}},
+			Options: options.Index().SetName("notebooks_author_parent"),
+		},
+	})
+
+	if err != nil {
+		log.Println("cannot create notebook indexes:", err)
+	}
+}
+
+func noteNotebookUpdate(notebookId *primitive.ObjectID) bson.M {
+	if notebookId == nil {
+		return bson.M{"$unset": bson.M{"notebook_id": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+
+	return bson.M{"$set": bson.M{"notebook_id": *notebookId, "updated_at": time.Now()}}
+}
+
+// getNotebookSubtreeIds returns ids of the notebook and all of its sub notebooks
+func getNotebookSubtreeIds(notebook *db.Notebook) ([]primitive.ObjectID, error) {
+	var descendants []db.Notebook
+	err := mgm.Coll(notebook).SimpleFind(
+		&descendants,
+		bson.M{"author": notebook.Author, "ancestors": notebook.ID},
+		options.Find().SetProjection(bson.M{field.ID: 1}),
+	)
+	if err != nil {
+		return nil, errors.New("cannot find sub notebooks")
+	}
+
+	ids := []primitive.ObjectID{notebook.ID}
+	for _, descendant := range descendants {
+		ids = append(ids, descendant.ID)
+	}
+
+	return ids, nil
+}
+
+// getNotebookHeight returns level count of the notebook with its deepest sub notebook, 1 if it has none
+func getNotebookHeight(notebook *db.Notebook) (int, error) {
+	var descendants []db.Notebook
+	err := mgm.Coll(notebook).SimpleFind(
+		&descendants,
+		bson.M{"author": notebook.Author, "ancestors": notebook.ID},
+		options.Find().SetProjection(bson.M{"ancestors": 1}),
+	)
+	if err != nil {
+		return 0, errors.New("cannot find sub notebooks")
+	}
+
+	height := 1
+	for _, descendant := range descendants {
+		if h := descendant.Depth() - notebook.Depth() + 1; h > height {
+			height = h
+		}
+	}
+
+	return height, nil
+}
+
+func errNotebookTooDeep() error {
+	return errors.New("notebooks cannot be nested deeper than " + strconv.Itoa(Config.NotebookMaxDepth) + " levels")
+}
+
+func containsObjectId(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sameObjectId(a *primitive.ObjectID, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+)
+
+func createTestNotebook(t *testing.T, userId primitive.ObjectID, name string, parent *db.Notebook) *db.Notebook {
+	t.Helper()
+
+	var parentId *primitive.ObjectID
+	if parent != nil {
+		parentId = &parent.ID
+	}
+	notebook, err := CreateNotebook(userId, name, parentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return notebook
+}
+
+func expectAncestors(t *testing.T, userId primitive.ObjectID, notebook *db.Notebook, ancestors ...*db.Notebook) {
+	t.Helper()
+
+	saved, err := GetNotebookById(userId, notebook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []primitive.ObjectID{}
+	for _, ancestor := range ancestors {
+		expected = append(expected, ancestor.ID)
+	}
+	if !reflect.DeepEqual(saved.Ancestors, expected) {
+		t.Fatalf("%s: expected ancestors %v, got %v", notebook.Name, expected, saved.Ancestors)
+	}
+}
+
+func TestUpdateNotebookMovesSubtree(t *testing.T) {
+	config := useTestDatabase(t)
+	config.NotebookMaxDepth = 5
+
+	userId := primitive.NewObjectID()
+	a := createTestNotebook(t, userId, "a", nil)
+	b := createTestNotebook(t, userId, "b", a)
+	c := createTestNotebook(t, userId, "c", b)
+	d := createTestNotebook(t, userId, "d", c)
+	x := createTestNotebook(t, userId, "x", nil)
+	y := createTestNotebook(t, userId, "y", x)
+
+	// b/c/d is moved from a to x/y, ancestors of its sub notebooks keep their path below b
+	if _, err := UpdateNotebook(userId, b.ID, "b", &y.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectAncestors(t, userId, b, x, y)
+	expectAncestors(t, userId, c, x, y, b)
+	expectAncestors(t, userId, d, x, y, b, c)
+	expectAncestors(t, userId, a)
+
+	// moving to top level removes the old path
+	if _, err := UpdateNotebook(userId, b.ID, "b", nil); err != nil {
+		t.Fatal(err)
+	}
+	expectAncestors(t, userId, b)
+	expectAncestors(t, userId, c, b)
+	expectAncestors(t, userId, d, b, c)
+
+	// renaming keeps the tree
+	renamed, err := UpdateNotebook(userId, c.ID, "renamed", &b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name != "renamed" {
+		t.Fatalf("expected notebook to be renamed, got %q", renamed.Name)
+	}
+	expectAncestors(t, userId, d, b, c)
+
+	if _, err = UpdateNotebook(userId, b.ID, "b", &d.ID); err == nil {
+		t.Fatal("expected notebook not to be moved into its sub notebook")
+	}
+	if _, err = UpdateNotebook(userId, b.ID, "b", &b.ID); err == nil {
+		t.Fatal("expected notebook not to be moved into itself")
+	}
+}
+
+func TestNotebookMaxDepth(t *testing.T) {
+	config := useTestDatabase(t)
+	config.NotebookMaxDepth = 3
+
+	userId := primitive.NewObjectID()
+	a := createTestNotebook(t, userId, "a", nil)
+	b := createTestNotebook(t, userId, "b", a)
+	c := createTestNotebook(t, userId, "c", b)
+
+	if _, err := CreateNotebook(userId, "too deep", &c.ID); err == nil {
+		t.Fatal("expected notebook under the deepest level to be rejected")
+	}
+
+	x := createTestNotebook(t, userId, "x", nil)
+	y := createTestNotebook(t, userId, "y", x)
+
+	// b/c has height 2, it fits under x but not under y
+	if _, err := UpdateNotebook(userId, b.ID, "b", &y.ID); err == nil {
+		t.Fatal("expected move over max depth to be rejected")
+	}
+	expectAncestors(t, userId, c, a, b)
+
+	if _, err := UpdateNotebook(userId, b.ID, "b", &x.ID); err != nil {
+		t.Fatalf("expected move to max depth, got %v", err)
+	}
+	expectAncestors(t, userId, c, x, b)
+
+	// renaming a notebook at max depth is not a move
+	if _, err := UpdateNotebook(userId, c.ID, "c2", &b.ID); err != nil {
+		t.Fatalf("expected rename at max depth, got %v", err)
+	}
+}
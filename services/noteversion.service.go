@@ -0,0 +1,231 @@
+package services
+
+import (
+	"errors"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+const noteVersionPruneInterval = time.Hour
+
+// ErrNoteConflict is returned when the note is updated by another request after it is read
+var ErrNoteConflict = errors.New("note is changed by another request, get it again and retry")
+
+// GetNoteVersions get paginated previous versions of a note, newest versions are first
+func GetNoteVersions(userId primitive.ObjectID, noteId primitive.ObjectID, page int, limit int) ([]db.NoteVersion, error) {
+	if _, err := GetNoteById(userId, noteId); err != nil {
+		return nil, err
+	}
+
+	var versions []db.NoteVersion
+
+	findOptions := options.Find().
+		SetSort(bson.M{"version": -1}).
+		SetSkip(int64(page * limit)).
+		SetLimit(int64(limit + 1))
+
+	err := mgm.Coll(&db.NoteVersion{}).SimpleFind(
+		&versions,
+		bson.M{"note": noteId, "author": userId},
+		findOptions,
+	)
+
+	if err != nil {
+		return nil, errors.New("cannot find note versions")
+	}
+
+	return versions, nil
+}
+
+// GetNoteVersion returns a version of the note, the current version is returned from the note itself
+func GetNoteVersion(userId primitive.ObjectID, noteId primitive.ObjectID, version int) (*db.NoteVersion, error) {
+	note, err := GetNoteById(userId, noteId)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == note.CurrentVersion() {
+		current := db.NewNoteVersion(note)
+		current.CreatedAt = note.UpdatedAt
+		return current, nil
+	}
+
+	noteVersion := &db.NoteVersion{}
+	err = mgm.Coll(noteVersion).First(bson.M{"note": noteId, "author": userId, "version": version}, noteVersion)
+	if err != nil {
+		return nil, errors.New("cannot find note version")
+	}
+
+	return noteVersion, nil
+}
+
+// RestoreNoteVersion sets title, content and tags of the note from a version, current state is kept as a version
+func RestoreNoteVersion(userId primitive.ObjectID, noteId primitive.ObjectID, version int) (*db.Note, error) {
+	note, err := GetNoteById(userId, noteId)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == note.CurrentVersion() {
+		return nil, errors.New("note is already at this version")
+	}
+
+	noteVersion, err := GetNoteVersion(userId, noteId, version)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := *note
+	note.Title = noteVersion.Title
+	note.Content = noteVersion.Content
+	note.Tags = db.NormalizeTags(noteVersion.Tags)
+
+	err = updateNoteWithVersion(&previous, note)
+	if err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+// PruneNoteVersions deletes versions of a note over NOTE_VERSION_MAX_COUNT
+func PruneNoteVersions(noteId primitive.ObjectID) error {
+	if Config.NoteVersionMaxCount <= 0 {
+		return nil
+	}
+
+	coll := mgm.Coll(&db.NoteVersion{})
+	oldestKept := &db.NoteVersion{}
+	err := coll.FindOne(
+		mgm.Ctx(),
+		bson.M{"note": noteId},
+		options.FindOne().SetSort(bson.M{"version": -1}).SetSkip(int64(Config.NoteVersionMaxCount-1)),
+	).Decode(oldestKept)
+
+	if err == nil {
+		_, err = coll.DeleteMany(mgm.Ctx(), bson.M{"note": noteId, "version": bson.M{"$lt": oldestKept.Version}})
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return errors.New("cannot prune note versions")
+	}
+
+	return nil
+}
+
+// PruneExpiredNoteVersions deletes versions of all notes older than NOTE_VERSION_MAX_AGE_DAYS
+func PruneExpiredNoteVersions() (int64, error) {
+	if Config.NoteVersionMaxAgeDays <= 0 {
+		return 0, nil
+	}
+
+	expiresAt := time.Now().AddDate(0, 0, -Config.NoteVersionMaxAgeDays)
+	deleteResult, err := mgm.Coll(&db.NoteVersion{}).DeleteMany(mgm.Ctx(), bson.M{"created_at": bson.M{"$lt": expiresAt}})
+	if err != nil {
+		return 0, errors.New("cannot prune note versions")
+	}
+
+	return deleteResult.DeletedCount, nil
+}
+
+// StartNoteVersionPruneJob prunes expired note versions on start and then every hour in background
+func StartNoteVersionPruneJob() {
+	if Config.NoteVersionMaxAgeDays <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(noteVersionPruneInterval)
+		defer ticker.Stop()
+
+		for {
+			pruned, err := PruneExpiredNoteVersions()
+			if err != nil {
+				log.Println(err)
+			} else if pruned > 0 {
+				log.Printf("Pruned %d expired note versions\n", pruned)
+			}
+
+			<-ticker.C
+		}
+	}()
+}
+
+// EnsureNoteVersionIndexes creates the indexes of version lists and expired version pruning
+func EnsureNoteVersionIndexes() {
+	_, err := mgm.Coll(&db.NoteVersion{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "note", Value: 1}, {Key: "version", Value: -1}},
+			Options: options.Index().SetName("note_versions_note_version").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("note_versions_created_at"),
+		},
+	})
+
+	if err != nil {
+		log.Println("cannot create note version indexes:", err)
+	}
+}
+
+// updateNoteWithVersion saves the note, previous state is saved as a version if title, content or tags are changed
+func updateNoteWithVersion(previous *db.Note, note *db.Note) error {
+	changed := previous.Title != note.Title || previous.Content != note.Content || !sameTags(previous.Tags, note.Tags)
+	if changed {
+		// a version has the same content in every snapshot of it, it can be saved by a failed or a concurrent update
+		// before, concurrent updates are rejected by the version filter of the note update below
+		err := mgm.Coll(&db.NoteVersion{}).Create(db.NewNoteVersion(previous))
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return errors.New("cannot save note version")
+		}
+		note.Version = previous.CurrentVersion() + 1
+	}
+
+	note.UpdatedAt = time.Now().UTC()
+	updateResult, err := mgm.Coll(note).UpdateOne(mgm.Ctx(), noteVersionFilter(previous), bson.M{"$set": note})
+	if err != nil {
+		return errors.New("cannot update")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrNoteConflict
+	}
+
+	DeleteNoteFromCache(note.Author, note.ID)
+
+	if changed {
+		_ = PruneNoteVersions(note.ID)
+	}
+
+	return nil
+}
+
+// noteVersionFilter matches the note only while it is not trashed and still at the version it is read
+func noteVersionFilter(note *db.Note) bson.M {
+	filter := bson.M{"_id": note.ID, "deleted_at": nil, "version": note.Version}
+	if note.CurrentVersion() == 1 {
+		// notes created before versioning have no version
+		filter["version"] = bson.M{"$in": bson.A{nil, 0, 1}}
+	}
+
+	return filter
+}
+
+func sameTags(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestUpdateNoteWithVersionConflict(t *testing.T) {
+	useTestDatabase(t)
+
+	userId := primitive.NewObjectID()
+	note, err := CreateNote(userId, "title", "first", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both requests read version 1 before any of them is saved
+	first, _ := GetNoteById(userId, note.ID)
+	second, _ := GetNoteById(userId, note.ID)
+
+	previous := *first
+	first.Content = "second"
+	if err = updateNoteWithVersion(&previous, first); err != nil {
+		t.Fatal(err)
+	}
+
+	previous = *second
+	second.Content = "third"
+	if err = updateNoteWithVersion(&previous, second); err != ErrNoteConflict {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+
+	saved, _ := GetNoteById(userId, note.ID)
+	if saved.Content != "second" || saved.Version != 2 {
+		t.Fatalf("expected first update to be kept, got %q at version %d", saved.Content, saved.Version)
+	}
+
+	versions, err := GetNoteVersions(userId, note.ID, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].Version != 1 || versions[0].Content != "first" {
+		t.Fatalf("expected only version 1 to be saved, got %+v", versions)
+	}
+}
+
+func TestUpdateNoteWithVersionLegacyNote(t *testing.T) {
+	useTestDatabase(t)
+
+	userId := primitive.NewObjectID()
+	note, err := CreateNote(userId, "title", "legacy", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// notes created before versioning have no version field
+	_, err = mgm.Coll(note).UpdateByID(mgm.Ctx(), note.ID, bson.M{"$unset": bson.M{"version": ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, _ := GetNoteById(userId, note.ID)
+	previous := *legacy
+	legacy.Content = "updated"
+	if err = updateNoteWithVersion(&previous, legacy); err != nil {
+		t.Fatalf("expected legacy note to be updated, got %v", err)
+	}
+
+	saved, _ := GetNoteById(userId, note.ID)
+	if saved.Content != "updated" || saved.Version != 2 {
+		t.Fatalf("expected legacy note at version 2, got %q at version %d", saved.Content, saved.Version)
+	}
+}
+
+func TestUpdateTrashedNoteConflict(t *testing.T) {
+	useTestDatabase(t)
+
+	userId := primitive.NewObjectID()
+	note, err := CreateNote(userId, "title", "content", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, _ := GetNoteById(userId, note.ID)
+
+	if err = DeleteNote(userId, note.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := *read
+	read.Content = "updated"
+	if err = updateNoteWithVersion(&previous, read); err != ErrNoteConflict {
+		t.Fatalf("expected trashed note not to be updated, got %v", err)
+	}
+
+	trashed := &db.Note{}
+	if err = mgm.Coll(trashed).FindByID(note.ID, trashed); err != nil {
+		t.Fatal(err)
+	}
+	if trashed.Content != "content" {
+		t.Fatalf("expected trashed note to be unchanged, got %q", trashed.Content)
+	}
+}
+
+func TestPruneExpiredNoteVersions(t *testing.T) {
+	config := useTestDatabase(t)
+	config.NoteVersionMaxAgeDays = 30
+
+	noteId := primitive.NewObjectID()
+	expired := &db.NoteVersion{Note: noteId, Version: 1}
+	kept := &db.NoteVersion{Note: primitive.NewObjectID(), Version: 1}
+	for _, version := range []*db.NoteVersion{expired, kept} {
+		if err := mgm.Coll(version).Create(version); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// versions of notes which are not edited again are pruned too
+	_, err := mgm.Coll(expired).UpdateByID(mgm.Ctx(), expired.ID, bson.M{"$set": bson.M{"created_at": time.Now().AddDate(0, 0, -31)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := PruneExpiredNoteVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Fatalf("expected 1 pruned version, got %d", pruned)
+	}
+	if err = mgm.Coll(kept).FindByID(kept.ID, &db.NoteVersion{}); err != nil {
+		t.Fatal("expected new version to be kept")
+	}
+
+	config.NoteVersionMaxAgeDays = 0
+	if pruned, _ = PruneExpiredNoteVersions(); pruned != 0 {
+		t.Fatal("expected versions to be kept forever without max age")
+	}
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oidcStateExpiresIn       = 10 * time.Minute
+	oidcKeysRefreshInterval  = time.Minute // unknown kid refetches keys at most once in this interval
+	oidcMaxResponseSize      = 1 << 20
+	oidcDefaultClientTimeout = 10 * time.Second
+)
+
+// OIDCProvider is an OpenID Connect provider users can sign in with,
+// its endpoints are discovered from the issuer
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+	HTTPClient   *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// OIDCIdentity is the user info read from a verified id token
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type oidcClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string   `json:"nonce"`
+	AuthorizedParty string   `json:"azp"`
+	Email           string   `json:"email"`
+	EmailVerified   oidcBool `json:"email_verified"`
+	Name            string   `json:"name"`
+}
+
+// oidcBool accepts boolean and string values, some providers send "true"
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	*b = strings.Trim(string(data), `"`) == "true"
+	return nil
+}
+
+func NewOIDCProvider(name string, config models.OIDCProviderConfig) *OIDCProvider {
+	scopes := strings.Fields(config.Scopes)
+	if !hasOpenIdScope(scopes) {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	return &OIDCProvider{
+		Name:         name,
+		Issuer:       config.Issuer,
+		ClientId:     config.ClientId,
+		ClientSecret: config.ClientSecret,
+		RedirectUrl:  config.RedirectUrl,
+		Scopes:       scopes,
+		HTTPClient:   &http.Client{Timeout: oidcDefaultClientTimeout},
+	}
+}
+
+var oidcProviders map[string]*OIDCProvider
+var oidcProvidersMu sync.Mutex
+
+// GetOIDCProvider finds a configured provider by name
+func GetOIDCProvider(name string) (*OIDCProvider, error) {
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+
+	provider, ok := loadOIDCProviders()[name]
+	if !ok {
+		return nil, errors.New("unknown oidc provider: " + name)
+	}
+
+	return provider, nil
+}
+
+// SetOIDCProvider adds or replaces a provider, useful for tests with a fake provider
+func SetOIDCProvider(provider *OIDCProvider) {
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+
+	loadOIDCProviders()[provider.Name] = provider
+}
+
+func loadOIDCProviders() map[string]*OIDCProvider {
+	if oidcProviders == nil {
+		oidcProviders = map[string]*OIDCProvider{}
+		if Config != nil {
+			for name, config := range Config.OIDC {
+				oidcProviders[name] = NewOIDCProvider(name, config)
+			}
+		}
+	}
+
+	return oidcProviders
+}
+
+// StartOIDCLogin saves a login state with nonce and hash of the PKCE verifier,
+// returns the authorization url of the provider and the verifier which is kept in the browser
+func StartOIDCLogin(provider *OIDCProvider) (string, string, error) {
+	state, err := randomOIDCString()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := randomOIDCString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomOIDCString()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	// abandoned logins are cleaned up here
+	_, _ = mgm.Coll(&db.OIDCState{}).DeleteMany(mgm.Ctx(), bson.M{"expires_at": bson.M{"$lt": time.Now()}})
+
+	oidcState := db.NewOIDCState(provider.Name, hashToken(state), hashToken(codeVerifier), nonce, time.Now().Add(oidcStateExpiresIn))
+	err = mgm.Coll(oidcState).Create(oidcState)
+	if err != nil {
+		return "", "", errors.New("cannot save oidc state")
+	}
+
+	return authURL, codeVerifier, nil
+}
+
+// FinishOIDCLogin uses the state, exchanges the authorization code and finds, links or creates the user.
+// codeVerifier is read from the cookie of the browser which started the login, so a code and state
+// of someone else's login cannot be finished in another browser.
+func FinishOIDCLogin(provider *OIDCProvider, code string, state string, codeVerifier string) (*db.User, error) {
+	oidcState := &db.OIDCState{}
+	err := mgm.Coll(oidcState).FindOneAndDelete(
+		mgm.Ctx(),
+		bson.M{
+			"provider":   provider.Name,
+			"state_hash": hashToken(state),
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+	).Decode(oidcState)
+	if err != nil {
+		return nil, errors.New("not valid or expired state")
+	}
+
+	if codeVerifier == "" || subtle.ConstantTimeCompare([]byte(hashToken(codeVerifier)), []byte(oidcState.VerifierHash)) != 1 {
+		return nil, errors.New("oidc login is not started in this browser")
+	}
+
+	rawIdToken, err := provider.Exchange(code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := provider.VerifyIdToken(rawIdToken, oidcState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return findOrCreateOIDCUser(provider.Name, identity)
+}
+
+// findOrCreateOIDCUser finds the user linked to the identity,
+// otherwise links or creates the user by the email verified by the provider
+func findOrCreateOIDCUser(providerName string, identity *OIDCIdentity) (*db.User, error) {
+	userIdentity := db.UserIdentity{Provider: providerName, Subject: identity.Subject}
+
+	user := &db.User{}
+	err := mgm.Coll(user).First(bson.M{"identities": bson.M{"$elemMatch": userIdentity}}, user)
+	if err == nil {
+		return user, nil
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errors.New("email is not verified by the provider")
+	}
+
+	user, err = FindUserByEmail(identity.Email)
+	if err != nil {
+		name := identity.Name
+		if name == "" {
+			name, _, _ = strings.Cut(identity.Email, "@")
+		}
+		return CreateExternalUser(name, identity.Email, userIdentity)
+	}
+
+	err = LinkUserIdentity(user, userIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// AuthCodeURL builds the authorization url with state, nonce and S256 PKCE challenge
+func (p *OIDCProvider) AuthCodeURL(state string, nonce string, codeVerifier string) (string, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientId)
+	query.Set("redirect_uri", p.RedirectUrl)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange sends the authorization code with PKCE verifier to token endpoint, returns the raw id token
+func (p *OIDCProvider) Exchange(code string, codeVerifier string) (string, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectUrl)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.ClientId)
+
+	request, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", errors.New("cannot exchange authorization code")
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.ClientId), url.QueryEscape(p.ClientSecret))
+	}
+
+	var tokenResponse struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	response, err := p.HTTPClient.Do(request)
+	if err != nil {
+		return "", errors.New("cannot exchange authorization code")
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(io.LimitReader(response.Body, oidcMaxResponseSize)).Decode(&tokenResponse)
+	if err != nil || response.StatusCode != http.StatusOK {
+		if tokenResponse.Error != "" {
+			return "", errors.New("cannot exchange authorization code: " + tokenResponse.Error)
+		}
+		return "", errors.New("cannot exchange authorization code")
+	}
+
+	if tokenResponse.IdToken == "" {
+		return "", errors.New("provider did not return an id token")
+	}
+
+	return tokenResponse.IdToken, nil
+}
+
+// VerifyIdToken checks signature, issuer, audience, expire date and nonce of the id token
+func (p *OIDCProvider) VerifyIdToken(rawIdToken string, nonce string) (*OIDCIdentity, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &oidcClaims{}
+	_, err = jwt.ParseWithClaims(
+		rawIdToken,
+		claims,
+		p.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+	)
+	if err != nil {
+		return nil, errors.New("not valid id token")
+	}
+
+	if claims.Issuer != discovery.Issuer || !claims.VerifyAudience(p.ClientId, true) {
+		return nil, errors.New("id token is not issued for this client")
+	}
+
+	// azp is required to be the client if there are other audiences
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientId {
+		return nil, errors.New("id token is not issued for this client")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("not valid id token nonce")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	return &OIDCIdentity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          strings.TrimSpace(claims.Name),
+	}, nil
+}
+
+// getDiscovery fetches openid configuration of the issuer once
+func (p *OIDCProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	discovery := &oidcDiscovery{}
+	err := p.getJSON(strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", discovery)
+	if err != nil {
+		return nil, errors.New("cannot discover oidc provider " + p.Name)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.Issuer, "/") {
+		return nil, errors.New("oidc provider " + p.Name + " has a different issuer")
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksUri == "" {
+		return nil, errors.New("oidc provider " + p.Name + " has missing endpoints")
+	}
+
+	p.discovery = discovery
+	return discovery, nil
+}
+
+// verificationKey finds the provider key of an id token by kid, keys are refetched for unknown kids
+func (p *OIDCProvider) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok := p.keys[kid]
+	if !ok && time.Since(p.keysFetchedAt) > oidcKeysRefreshInterval {
+		if err := p.fetchKeys(); err != nil {
+			return nil, err
+		}
+		key, ok = p.keys[kid]
+	}
+
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	return key, nil
+}
+
+// fetchKeys loads RSA and P-256 signing keys from jwks_uri, p.mu has to be locked
+func (p *OIDCProvider) fetchKeys() error {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+
+	p.keysFetchedAt = time.Now()
+	err := p.getJSON(p.discovery.JwksUri, &jwks)
+	if err != nil {
+		return errors.New("cannot fetch oidc provider keys")
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		switch {
+		case jwk.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case jwk.Kty == "EC" && jwk.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	p.keys = keys
+	return nil
+}
+
+func (p *OIDCProvider) getJSON(endpoint string, v interface{}) error {
+	response, err := p.HTTPClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return errors.New("unexpected status: " + response.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, oidcMaxResponseSize)).Decode(v)
+}
+
+func randomOIDCString() (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", errors.New("cannot create oidc state")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+func hasOpenIdScope(scopes []string) bool {
+	for _, scope := range scopes {
+		if scope == "openid" {
+			return true
+		}
+	}
+
+	return false
+}
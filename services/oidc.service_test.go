@@ -0,0 +1,414 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fakeOIDCClientId = "test-client"
+
+// fakeOIDCServer is an OpenID Connect provider with discovery, token and jwks endpoints.
+// Authorization endpoint is not served, tests call authorize with the authorization url instead.
+type fakeOIDCServer struct {
+	*httptest.Server
+
+	key       *rsa.PrivateKey
+	signKey   *rsa.PrivateKey // key which signs id tokens, differs from key for bad signatures
+	subject   string
+	email     string
+	verified  bool
+	nonce     string // overrides nonce of the authorization request if set
+	expiresIn time.Duration
+
+	mu    sync.Mutex
+	codes map[string]fakeOIDCAuthorization
+}
+
+type fakeOIDCAuthorization struct {
+	nonce     string
+	challenge string
+}
+
+func newFakeOIDCServer(t *testing.T) *fakeOIDCServer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeOIDCServer{
+		key:       key,
+		signKey:   key,
+		subject:   "subject-1",
+		email:     "oidc@example.com",
+		verified:  true,
+		expiresIn: time.Minute,
+		codes:     map[string]fakeOIDCAuthorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", fake.discovery)
+	mux.HandleFunc("/token", fake.token)
+	mux.HandleFunc("/jwks", fake.jwks)
+	fake.Server = httptest.NewServer(mux)
+	t.Cleanup(fake.Close)
+
+	return fake
+}
+
+// provider returns a provider of the fake server and registers it with SetOIDCProvider
+func (f *fakeOIDCServer) provider() *OIDCProvider {
+	provider := NewOIDCProvider("fake", models.OIDCProviderConfig{
+		Issuer:      f.URL,
+		ClientId:    fakeOIDCClientId,
+		RedirectUrl: "http://localhost:3000/oidc/callback",
+		Scopes:      "email profile",
+	})
+	provider.HTTPClient = f.Client()
+	SetOIDCProvider(provider)
+
+	return provider
+}
+
+// authorize acts as the user approving the login, returns the code and state the provider redirects with
+func (f *fakeOIDCServer) authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != fakeOIDCClientId {
+		t.Fatalf("unexpected authorization url %s", authURL)
+	}
+
+	code, err := randomOIDCString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.codes[code] = fakeOIDCAuthorization{nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+
+	return code, query.Get("state")
+}
+
+func (f *fakeOIDCServer) discovery(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(gin.H{
+		"issuer":                 f.URL,
+		"authorization_endpoint": f.URL + "/authorize",
+		"token_endpoint":         f.URL + "/token",
+		"jwks_uri":               f.URL + "/jwks",
+	})
+}
+
+func (f *fakeOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	authorization, ok := f.codes[r.PostFormValue("code")]
+	delete(f.codes, r.PostFormValue("code"))
+	f.mu.Unlock()
+
+	// codes are used once and only with the verifier of their challenge
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != authorization.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(gin.H{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := authorization.nonce
+	if f.nonce != "" {
+		nonce = f.nonce
+	}
+
+	_ = json.NewEncoder(w).Encode(gin.H{"id_token": f.idToken(nonce), "token_type": "Bearer"})
+}
+
+func (f *fakeOIDCServer) jwks(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(gin.H{"keys": []gin.H{{
+		"kty": "RSA",
+		"kid": "fake-key",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+	}}})
+}
+
+func (f *fakeOIDCServer) idToken(nonce string) string {
+	claims := &oidcClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    f.URL,
+			Subject:   f.subject,
+			Audience:  jwt.ClaimStrings{fakeOIDCClientId},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(f.expiresIn)),
+		},
+		Nonce:         nonce,
+		Email:         f.email,
+		EmailVerified: oidcBool(f.verified),
+		Name:          "OIDC User",
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "fake-key"
+	signed, _ := token.SignedString(f.signKey)
+	return signed
+}
+
+func TestOIDCVerifyIdToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(fake *fakeOIDCServer)
+		nonce  string
+		valid  bool
+	}{
+		{name: "valid", modify: func(fake *fakeOIDCServer) {}, nonce: "nonce", valid: true},
+		{name: "bad nonce", modify: func(fake *fakeOIDCServer) {}, nonce: "other nonce", valid: false},
+		{name: "bad signature", modify: func(fake *fakeOIDCServer) { fake.signKey = otherKey }, nonce: "nonce", valid: false},
+		{name: "expired", modify: func(fake *fakeOIDCServer) { fake.expiresIn = -time.Minute }, nonce: "nonce", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFakeOIDCServer(t)
+			test.modify(fake)
+
+			identity, err := fake.provider().VerifyIdToken(fake.idToken("nonce"), test.nonce)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got error %v", test.valid, err)
+			}
+			if test.valid && (identity.Subject != fake.subject || identity.Email != fake.email || !identity.EmailVerified) {
+				t.Fatalf("unexpected identity %+v", identity)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyIdTokenOtherIssuer(t *testing.T) {
+	fake := newFakeOIDCServer(t)
+	other := newFakeOIDCServer(t)
+	other.key = fake.key
+	other.signKey = fake.key
+
+	if _, err := fake.provider().VerifyIdToken(other.idToken("nonce"), "nonce"); err == nil {
+		t.Fatal("expected id token of another issuer to be rejected")
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	useTestDatabase(t)
+	fake := newFakeOIDCServer(t)
+	provider, err := GetOIDCProvider(fake.provider().Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, codeVerifier, err := StartOIDCLogin(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := fake.authorize(t, authURL)
+
+	user, err := FinishOIDCLogin(provider, code, state, codeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != fake.email || !user.MailVerified || user.Password != "" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	// next login finds the user by its identity
+	authURL, codeVerifier, _ = StartOIDCLogin(provider)
+	code, state = fake.authorize(t, authURL)
+	again, err := FinishOIDCLogin(provider, code, state, codeVerifier)
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("expected the same user, got %v %v", again, err)
+	}
+}
+
+func TestOIDCLoginFailures(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(fake *fakeOIDCServer)
+		// finish changes the callback of a started login
+		finish func(t *testing.T, provider *OIDCProvider, code string, state string, codeVerifier string) error
+	}{
+		{
+			name:   "bad nonce",
+			modify: func(fake *fakeOIDCServer) { fake.nonce = "replayed nonce" },
+		},
+		{
+			name:   "bad signature",
+			modify: func(fake *fakeOIDCServer) { fake.signKey = otherKey },
+		},
+		{
+			name:   "unverified email",
+			modify: func(fake *fakeOIDCServer) { fake.verified = false },
+		},
+		{
+			name: "missing verifier cookie",
+			finish: func(t *testing.T, provider *OIDCProvider, code string, state string, codeVerifier string) error {
+				_, err := FinishOIDCLogin(provider, code, state, "")
+				return err
+			},
+		},
+		{
+			name: "verifier of another browser",
+			finish: func(t *testing.T, provider *OIDCProvider, code string, state string, codeVerifier string) error {
+				_, otherVerifier, err := StartOIDCLogin(provider)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = FinishOIDCLogin(provider, code, state, otherVerifier)
+				return err
+			},
+		},
+		{
+			name: "replayed state",
+			finish: func(t *testing.T, provider *OIDCProvider, code string, state string, codeVerifier string) error {
+				if _, err := FinishOIDCLogin(provider, code, state, codeVerifier); err != nil {
+					t.Fatal(err)
+				}
+				_, err := FinishOIDCLogin(provider, code, state, codeVerifier)
+				return err
+			},
+		},
+		{
+			name: "expired state",
+			finish: func(t *testing.T, provider *OIDCProvider, code string, state string, codeVerifier string) error {
+				_, err := mgm.Coll(&db.OIDCState{}).UpdateMany(
+					mgm.Ctx(),
+					bson.M{"state_hash": hashToken(state)},
+					bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Second)}},
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = FinishOIDCLogin(provider, code, state, codeVerifier)
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTestDatabase(t)
+			fake := newFakeOIDCServer(t)
+			if test.modify != nil {
+				test.modify(fake)
+			}
+			provider := fake.provider()
+
+			authURL, codeVerifier, err := StartOIDCLogin(provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, state := fake.authorize(t, authURL)
+
+			if test.finish == nil {
+				_, err = FinishOIDCLogin(provider, code, state, codeVerifier)
+			} else {
+				err = test.finish(t, provider, code, state, codeVerifier)
+			}
+			if err == nil {
+				t.Fatal("expected login to fail")
+			}
+		})
+	}
+}
+
+func TestOIDCLoginLinksUnverifiedEmail(t *testing.T) {
+	useTestDatabase(t)
+	fake := newFakeOIDCServer(t)
+	provider := fake.provider()
+
+	// someone else registered with the email before its owner signs in with the provider
+	squatter, err := CreateUser("Squatter", fake.email, "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := CreateApiKey(squatter, "squatter", []string{db.PermissionNotesRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _, err := GenerateAccessTokens(squatter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, codeVerifier, _ := StartOIDCLogin(provider)
+	code, state := fake.authorize(t, authURL)
+	user, err := FinishOIDCLogin(provider, code, state, codeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != squatter.ID || !user.MailVerified || len(user.Identities) != 1 {
+		t.Fatalf("expected the account to be linked, got %+v", user)
+	}
+	if CheckUserPassword(user, "correct horse battery staple") {
+		t.Fatal("expected password of the unverified account to be removed")
+	}
+	if _, err = VerifyApiKey(apiKey.Key); err == nil {
+		t.Fatal("expected api keys of the unverified account to be revoked")
+	}
+	if _, err = VerifyToken(session.Token, db.TokenTypeAccess); err == nil {
+		t.Fatal("expected tokens of the unverified account to be revoked")
+	}
+}
+
+func TestOIDCVerifierCookie(t *testing.T) {
+	useTestConfig(t)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	SetOIDCVerifierCookie(c, "verifier")
+
+	cookie := recorder.Result().Cookies()[0]
+	if cookie.Name != OIDCVerifierCookie || cookie.Value != "verifier" || !cookie.HttpOnly ||
+		cookie.SameSite != http.SameSiteLaxMode || cookie.Path != oidcCookiePath || !cookie.Secure {
+		t.Fatalf("unexpected cookie %+v", cookie)
+	}
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, oidcCookiePath+"/fake/callback", nil)
+	c.Request.AddCookie(&http.Cookie{Name: OIDCVerifierCookie, Value: "verifier"})
+
+	if value := PopOIDCVerifierCookie(c); value != "verifier" {
+		t.Fatalf("expected verifier, got %q", value)
+	}
+	if header := recorder.Header().Get("Set-Cookie"); !strings.Contains(header, "Max-Age=0") {
+		t.Fatalf("expected cookie to be removed, got %q", header)
+	}
+}
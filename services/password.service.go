@@ -0,0 +1,79 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+// SendPasswordResetMail mails a reset token if a user has the email, unknown emails are ignored silently
+func SendPasswordResetMail(email string) {
+	user, err := FindUserByEmail(email)
+	if err != nil {
+		return
+	}
+
+	_ = BlacklistUserTokensByType(user.ID, db.TokenTypeResetPassword)
+
+	expiresAt := time.Now().Add(time.Duration(Config.ResetPasswordExpirationMinutes) * time.Minute)
+	token, err := CreateOneTimeToken(user.ID, db.TokenTypeResetPassword, expiresAt)
+	if err != nil {
+		return
+	}
+
+	body := "Hi " + user.Name + ",\n\n" +
+		"You can reset your password by opening the link below:\n\n" +
+		Config.AppUrl + "/reset-password?token=" + token.Token + "\n\n" +
+		"The link expires at " + expiresAt.Format("2006-01-02 15:04:05") + ". " +
+		"If you didn't request a password reset, you can ignore this email."
+
+	_ = SendMail(user.Email, "Reset your password", body)
+}
+
+// ResetPassword uses a reset token, sets the new password and revokes every token of the user
+func ResetPassword(token string, plainPassword string) error {
+	tokenModel, err := FindOneTimeToken(token, db.TokenTypeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	user, err := FindUserById(tokenModel.User)
+	if err != nil {
+		return err
+	}
+
+	// token is not used if the new password is rejected, so the user can try another one
+	err = CheckUserNewPassword(user, plainPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = UseOneTimeToken(token, db.TokenTypeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	err = UpdateUserPassword(user, plainPassword)
+	if err != nil {
+		return err
+	}
+
+	// reset link proves the ownership of the email
+	if !user.MailVerified {
+		_, _ = mgm.Coll(user).UpdateOne(
+			mgm.Ctx(),
+			bson.M{field.ID: user.ID},
+			bson.M{"$set": bson.M{"mail_verified": true}},
+		)
+	}
+
+	// whoever requested the reset may not own the account, api keys created by someone else are revoked
+	err = RevokeUserApiKeys(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return BlacklistUserTokens(user.ID)
+}
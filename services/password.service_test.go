@@ -0,0 +1,32 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"testing"
+	"time"
+)
+
+func TestResetPasswordRevokesApiKeys(t *testing.T) {
+	useTestDatabase(t)
+
+	user, err := CreateUser("Test", "reset@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := CreateApiKey(user, "ci", []string{db.PermissionNotesRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := CreateOneTimeToken(user.ID, db.TokenTypeResetPassword, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ResetPassword(token.Token, "another horse battery staple"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = VerifyApiKey(apiKey.Key); err == nil {
+		t.Fatal("expected api key to be revoked after password reset")
+	}
+}
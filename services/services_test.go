@@ -0,0 +1,115 @@
+package services
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"sync"
+	"testing"
+)
+
+var testDatabaseOnce sync.Once
+var testDatabaseErr error
+
+// useTestConfig replaces Config with defaults of LoadConfig for the duration of the test
+func useTestConfig(t *testing.T) *models.EnvConfig {
+	t.Helper()
+
+	previous := Config
+	Config = &models.EnvConfig{
+		MongodbDatabase:            "go_starter_test",
+		JWTSecretKey:               "test-secret",
+		JWTAlgorithm:               JWTAlgorithmHS256,
+		JWTAccessExpirationMinutes: 15,
+		JWTRefreshExpirationDays:   7,
+		CookieSecure:               true,
+		CookieSameSite:             "lax",
+		AppUrl:                     "http://localhost:8080",
+		MailDriver:                 MailDriverLog,
+		VerifyEmailExpirationHours: 24,
+		VerifyEmailResendSeconds:   60,
+		PasswordMinLength:          8,
+		PasswordMaxLength:          128,
+		PasswordMinEntropy:         40,
+		PasswordHasher:             "argon2id",
+		Argon2MemoryKiB:            19456,
+		Argon2Iterations:           2,
+		Argon2Parallelism:          1,
+		BcryptCost:                 10,
+		NoteVersionMaxCount:        50,
+		TrashRetentionDays:         30,
+		Mode:                       "test",
+	}
+	t.Cleanup(func() {
+		Config = previous
+	})
+
+	return Config
+}
+
+// useTestDatabase connects to TEST_MONGO_URI and drops the test database, tests are skipped without it
+func useTestDatabase(t *testing.T) *models.EnvConfig {
+	t.Helper()
+
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+
+	config := useTestConfig(t)
+	testDatabaseOnce.Do(func() {
+		testDatabaseErr = mgm.SetDefaultConfig(nil, config.MongodbDatabase, options.Client().ApplyURI(uri))
+	})
+	if testDatabaseErr != nil {
+		t.Fatal(testDatabaseErr)
+	}
+
+	_, _, database, _ := mgm.DefaultConfigs()
+	if err := database.Drop(mgm.Ctx()); err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+type sentMail struct {
+	to      string
+	subject string
+	body    string
+}
+
+// testMailer keeps sent mails in memory
+type testMailer struct {
+	mu    sync.Mutex
+	mails []sentMail
+}
+
+func (m *testMailer) Send(to string, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mails = append(m.mails, sentMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+func (m *testMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.mails)
+}
+
+// useTestMailer replaces the mailer for the duration of the test
+func useTestMailer(t *testing.T) *testMailer {
+	t.Helper()
+
+	previous := GetMailer()
+	m := &testMailer{}
+	SetMailer(m)
+	t.Cleanup(func() {
+		SetMailer(previous)
+	})
+
+	return m
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const JWTAlgorithmHS256 = "HS256"
+
+// signingKey asymmetric key pair identified by kid
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+var signingKeys = map[string]*signingKey{}
+var activeSigningKey *signingKey
+
+// LoadSigningKeys loads every "<kid>.pem" key in JWT_KEYS_PATH.
+// The private key with JWT_KEY_ID signs new tokens, others only verify tokens issued before rotation,
+// so retired keys can be public keys only.
+func LoadSigningKeys() {
+	if Config.JWTKeysPath == "" {
+		if Config.JWTAlgorithm != JWTAlgorithmHS256 {
+			panic("JWT_KEYS_PATH is required for " + Config.JWTAlgorithm)
+		}
+		return
+	}
+
+	files, err := filepath.Glob(filepath.Join(Config.JWTKeysPath, "*.pem"))
+	if err != nil {
+		panic(err)
+	}
+
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := loadSigningKey(kid, file)
+		if err != nil {
+			panic(fmt.Sprintf("cannot load signing key %s: %s", kid, err))
+		}
+		signingKeys[kid] = key
+	}
+
+	if Config.JWTAlgorithm == JWTAlgorithmHS256 {
+		log.Printf("Loaded %d verification keys\n", len(signingKeys))
+		return
+	}
+
+	key, ok := signingKeys[Config.JWTKeyId]
+	if !ok {
+		panic("cannot find signing key with JWT_KEY_ID: " + Config.JWTKeyId)
+	}
+
+	if key.method.Alg() != Config.JWTAlgorithm {
+		panic("signing key " + key.kid + " cannot be used with " + Config.JWTAlgorithm)
+	}
+
+	if key.private == nil {
+		panic("signing key " + key.kid + " is a public key, active key must be a private key")
+	}
+
+	activeSigningKey = key
+	log.Printf("Loaded %d signing keys, active key: %s\n", len(signingKeys), key.kid)
+}
+
+func loadSigningKey(kid string, file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("not a pem file")
+	}
+
+	var private interface{}
+	var public interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid, private: private}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		public = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		public = &k.PublicKey
+	case ed25519.PrivateKey:
+		public = k.Public()
+	case nil:
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 curve is supported")
+		}
+		key.method, key.public = jwt.SigningMethodES256, k
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return key, nil
+}
+
+// signClaims signs claims with the active key, falls back to HS256 with JWT_SECRET
+func signClaims(claims jwt.Claims) (string, error) {
+	if activeSigningKey == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(Config.JWTSecretKey))
+	}
+
+	token := jwt.NewWithClaims(activeSigningKey.method, claims)
+	token.Header["kid"] = activeSigningKey.kid
+	return token.SignedString(activeSigningKey.private)
+}
+
+// verificationKey finds the key of a token by kid header. Tokens without kid are verified with JWT_SECRET
+// only if HS256 is the active algorithm or JWT_LEGACY_HS256 keeps accepting them after moving to key pairs.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if token.Method != jwt.SigningMethodHS256 || Config.JWTSecretKey == "" {
+			return nil, errors.New("unexpected signing method")
+		}
+		if Config.JWTAlgorithm != JWTAlgorithmHS256 && !Config.JWTLegacyHS256 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(Config.JWTSecretKey), nil
+	}
+
+	key, ok := signingKeys[kid]
+	if !ok || key.method.Alg() != token.Method.Alg() {
+		return nil, errors.New("unknown signing key")
+	}
+
+	return key.public, nil
+}
+
+// GetJWKS returns public keys as a JSON Web Key Set
+func GetJWKS() gin.H {
+	kids := make([]string, 0, len(signingKeys))
+	for kid := range signingKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]gin.H, 0, len(kids))
+	for _, kid := range kids {
+		key := signingKeys[kid]
+		jwk := gin.H{"kid": key.kid, "alg": key.method.Alg(), "use": "sig"}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = encodeBase64URL(public.N.Bytes())
+			jwk["e"] = encodeBase64URL(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk["kty"] = "EC"
+			jwk["crv"] = "P-256"
+			jwk["x"] = encodeBase64URL(public.X.FillBytes(make([]byte, 32)))
+			jwk["y"] = encodeBase64URL(public.Y.FillBytes(make([]byte, 32)))
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = encodeBase64URL(public)
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	return gin.H{"keys": keys}
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v4"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useTestSigningKeys clears loaded keys and restores them after the test
+func useTestSigningKeys(t *testing.T) {
+	t.Helper()
+
+	previousKeys, previousActive := signingKeys, activeSigningKey
+	signingKeys, activeSigningKey = map[string]*signingKey{}, nil
+	t.Cleanup(func() {
+		signingKeys, activeSigningKey = previousKeys, previousActive
+	})
+}
+
+func writePEM(t *testing.T, dir string, kid string, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "user",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+}
+
+func parseTestToken(token string) error {
+	_, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, verificationKey)
+	return err
+}
+
+func TestVerificationKeyWithoutKid(t *testing.T) {
+	secretSigned, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs512Signed, err := jwt.NewWithClaims(jwt.SigningMethodHS512, testClaims()).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		algorithm string
+		legacy    bool
+		token     string
+		valid     bool
+	}{
+		{name: "active hs256", algorithm: "HS256", token: secretSigned, valid: true},
+		{name: "active rs256", algorithm: "RS256", token: secretSigned, valid: false},
+		{name: "active rs256 with legacy hs256", algorithm: "RS256", legacy: true, token: secretSigned, valid: true},
+		{name: "other hmac method", algorithm: "HS256", token: hs512Signed, valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := useTestConfig(t)
+			config.JWTAlgorithm = test.algorithm
+			config.JWTLegacyHS256 = test.legacy
+
+			err := parseTestToken(test.token)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestLoadSigningKeysWithPublicRetiredKey(t *testing.T) {
+	useTestSigningKeys(t)
+	config := useTestConfig(t)
+
+	retired, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeDer, err := x509.MarshalECPrivateKey(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writePEM(t, dir, "retired", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&retired.PublicKey))
+	writePEM(t, dir, "active", "EC PRIVATE KEY", activeDer)
+
+	config.JWTAlgorithm = "ES256"
+	config.JWTKeysPath = dir
+	config.JWTKeyId = "active"
+	LoadSigningKeys()
+
+	if activeSigningKey == nil || activeSigningKey.kid != "active" {
+		t.Fatalf("expected active key to be loaded")
+	}
+	if signingKeys["retired"].private != nil {
+		t.Fatalf("expected retired key without private key")
+	}
+
+	// tokens signed before rotation are still verified with the public key
+	retiredToken := jwt.NewWithClaims(jwt.SigningMethodRS256, testClaims())
+	retiredToken.Header["kid"] = "retired"
+	signed, err := retiredToken.SignedString(retired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = parseTestToken(signed); err != nil {
+		t.Fatalf("expected token of retired key to be valid: %v", err)
+	}
+
+	signed, err = signClaims(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = parseTestToken(signed); err != nil {
+		t.Fatalf("expected token of active key to be valid: %v", err)
+	}
+}
+
+func TestLoadSigningKeysRejectsPublicActiveKey(t *testing.T) {
+	useTestSigningKeys(t)
+	config := useTestConfig(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writePEM(t, dir, "active", "PUBLIC KEY", der)
+
+	config.JWTAlgorithm = "ES256"
+	config.JWTKeysPath = dir
+	config.JWTKeyId = "active"
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected public active key to be rejected")
+		}
+	}()
+	LoadSigningKeys()
+}
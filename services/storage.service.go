@@ -90,3 +90,17 @@ func GetNoteFromCache(userId primitive.ObjectID, noteId primitive.ObjectID) (*mo
 	err := GetRedisCache().Get(context.TODO(), noteCacheKey, note)
 	return note, err
 }
+
+func DeleteNoteFromCache(userId primitive.ObjectID, noteId primitive.ObjectID) {
+	if !Config.UseRedis {
+		return
+	}
+
+	_ = GetRedisCache().Delete(context.TODO(), getNoteCacheKey(userId, noteId))
+}
+
+func DeleteNotesFromCache(userId primitive.ObjectID, noteIds []primitive.ObjectID) {
+	for _, noteId := range noteIds {
+		DeleteNoteFromCache(userId, noteId)
+	}
+}
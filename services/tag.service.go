@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// GetTags returns tags of the user with their note counts, most used tags are first
+func GetTags(userId primitive.ObjectID) ([]db.TagCount, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"author": userId, "deleted_at": nil}},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+
+	cursor, err := mgm.Coll(&db.Note{}).Aggregate(mgm.Ctx(), pipeline)
+	if err != nil {
+		return nil, errors.New("cannot get tags")
+	}
+
+	tags := []db.TagCount{}
+	if err = cursor.All(mgm.Ctx(), &tags); err != nil {
+		return nil, errors.New("cannot get tags")
+	}
+
+	return tags, nil
+}
+
+// RenameTag renames a tag on every note of the user including notes in trash, returns count of updated notes
+func RenameTag(userId primitive.ObjectID, tag string, name string) (int64, error) {
+	return MergeTags(userId, []string{tag}, name)
+}
+
+// MergeTags replaces source tags with the target tag on every note of the user in one update,
+// notes which already have the target keep only one of it. Notes in trash are updated too,
+// so restored notes don't bring back merged tags
+func MergeTags(userId primitive.ObjectID, sources []string, target string) (int64, error) {
+	sources = db.NormalizeTags(sources)
+	targets := db.NormalizeTags([]string{target})
+	if len(sources) == 0 || len(targets) == 0 {
+		return 0, errors.New("tags cannot be empty")
+	}
+	target = targets[0]
+
+	replaced := bson.M{"$map": bson.M{
+		"input": "$tags",
+		"as":    "tag",
+		"in":    bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$$tag", sources}}, target, "$$tag"}},
+	}}
+	// removes duplicates without changing the order, $setUnion doesn't keep it
+	unique := bson.M{"$reduce": bson.M{
+		"input":        replaced,
+		"initialValue": bson.A{},
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$in": bson.A{"$$this", "$$value"}},
+			"$$value",
+			bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$this"}}},
+		}},
+	}}
+
+	filter := bson.M{"author": userId, "tags": bson.M{"$in": sources}}
+	noteIds, err := findNoteIds(filter)
+	if err != nil {
+		return 0, errors.New("cannot update tags")
+	}
+
+	result, err := mgm.Coll(&db.Note{}).UpdateMany(
+		mgm.Ctx(),
+		filter,
+		bson.A{bson.M{"$set": bson.M{"tags": unique, "updated_at": time.Now()}}},
+	)
+	if err != nil {
+		return 0, errors.New("cannot update tags")
+	}
+	DeleteNotesFromCache(userId, noteIds)
+
+	return result.ModifiedCount, nil
+}
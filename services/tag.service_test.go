@@ -0,0 +1,131 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func createTestNote(t *testing.T, userId primitive.ObjectID, title string, tags ...string) *db.Note {
+	t.Helper()
+
+	note, err := CreateNote(userId, title, "content", tags, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return note
+}
+
+func TestMergeTags(t *testing.T) {
+	useTestDatabase(t)
+
+	userId := primitive.NewObjectID()
+	both := createTestNote(t, userId, "both", "go", "golang", "db")
+	target := createTestNote(t, userId, "target", "mongo", "golang", "go", "api")
+	untouched := createTestNote(t, userId, "untouched", "db")
+	trashed := createTestNote(t, userId, "trashed", "golang")
+	other := createTestNote(t, primitive.NewObjectID(), "other", "golang")
+	if err := DeleteNote(userId, trashed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := MergeTags(userId, []string{"Golang", "go"}, " Go ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 3 {
+		t.Fatalf("expected 3 updated notes, got %d", updated)
+	}
+
+	expected := map[*db.Note][]string{
+		both:      {"go", "db"},
+		target:    {"mongo", "go", "api"},
+		untouched: {"db"},
+		trashed:   {"go"},
+		other:     {"golang"},
+	}
+	for note, tags := range expected {
+		saved := &db.Note{}
+		if err = mgm.Coll(saved).FindByID(note.ID, saved); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(saved.Tags, tags) {
+			t.Errorf("%s: expected tags %v without duplicates in order, got %v", note.Title, tags, saved.Tags)
+		}
+	}
+
+	if _, err = MergeTags(userId, []string{" "}, "go"); err == nil {
+		t.Fatal("expected empty source tags to be rejected")
+	}
+}
+
+func TestGetNotesTagFilter(t *testing.T) {
+	useTestDatabase(t)
+
+	userId := primitive.NewObjectID()
+	createTestNote(t, userId, "go db", "go", "db")
+	createTestNote(t, userId, "go", "go")
+	createTestNote(t, userId, "db", "db")
+	createTestNote(t, userId, "none")
+	trashed := createTestNote(t, userId, "trashed", "go", "db")
+	if err := DeleteNote(userId, trashed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		tags     []string
+		matchAll bool
+		titles   []string
+	}{
+		{name: "no tags", titles: []string{"db", "go", "go db", "none"}},
+		{name: "any", tags: []string{"go", "db"}, titles: []string{"db", "go", "go db"}},
+		{name: "all", tags: []string{"go", "db"}, matchAll: true, titles: []string{"go db"}},
+		{name: "normalized", tags: []string{" GO "}, matchAll: true, titles: []string{"go", "go db"}},
+		{name: "unknown", tags: []string{"rust"}, titles: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notes, err := GetNotes(userId, test.tags, test.matchAll, 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var titles []string
+			for _, note := range notes {
+				titles = append(titles, note.Title)
+			}
+			sort.Strings(titles)
+			if !reflect.DeepEqual(titles, test.titles) {
+				t.Fatalf("expected %v, got %v", test.titles, titles)
+			}
+		})
+	}
+}
+
+func TestGetTags(t *testing.T) {
+	useTestDatabase(t)
+
+	userId := primitive.NewObjectID()
+	createTestNote(t, userId, "first", "go", "db")
+	createTestNote(t, userId, "second", "go")
+	trashed := createTestNote(t, userId, "trashed", "go", "api")
+	if err := DeleteNote(userId, trashed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := GetTags(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []db.TagCount{{Tag: "go", Count: 2}, {Tag: "db", Count: 1}}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected %v, got %v", expected, tags)
+	}
+}
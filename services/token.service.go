@@ -1,6 +1,11 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
 	"github.com/golang-jwt/jwt/v4"
@@ -8,32 +13,49 @@ import (
 	"github.com/kamva/mgm/v3/field"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
 	"time"
 )
 
-// CreateToken create a new token record
-func CreateToken(user *db.User, tokenType string, expiresAt time.Time) (*db.Token, error) {
+var (
+	ErrTokenMissing   = errors.New("token is missing")
+	ErrTokenMalformed = errors.New("token is malformed")
+	ErrTokenScheme    = errors.New("authorization scheme is not supported")
+	ErrTokenInvalid   = errors.New("token is not valid")
+	ErrTokenExpired   = errors.New("token is expired")
+	ErrTokenRevoked   = errors.New("token is revoked")
+)
+
+// CreateToken create a new token record, device is the client which the token is issued to
+func CreateToken(user *db.User, family primitive.ObjectID, tokenType string, expiresAt time.Time, device *db.TokenDevice) (*db.Token, error) {
+	jti := primitive.NewObjectID().Hex()
 	claims := &db.UserClaims{
 		Email: user.Email,
+		Role:  user.Role,
 		Type:  tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			Subject:   user.ID.Hex(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(Config.JWTSecretKey))
+	tokenString, err := signClaims(claims)
 	if err != nil {
 		return nil, errors.New("cannot create access token")
 	}
 
-	tokenModel := db.NewToken(user.ID, tokenString, tokenType, expiresAt)
+	// only the hash is saved, plain token is returned to the client once
+	tokenModel := db.NewToken(user.ID, family, jti, hashToken(tokenString), tokenType, expiresAt)
+	tokenModel.Device = device
 	err = mgm.Coll(tokenModel).Create(tokenModel)
 	if err != nil {
 		return nil, errors.New("cannot save access token to db")
 	}
+	tokenModel.Token = tokenString
 
 	return tokenModel, nil
 }
@@ -49,17 +71,173 @@ func DeleteTokenById(tokenId primitive.ObjectID) error {
 	return nil
 }
 
-// GenerateAccessTokens generates "access" and "refresh" token for user
-func GenerateAccessTokens(user *db.User) (*db.Token, *db.Token, error) {
+// GetActiveSessions finds not expired and not revoked refresh tokens of the user
+func GetActiveSessions(userId primitive.ObjectID) ([]db.Token, error) {
+	var tokens []db.Token
+	err := mgm.Coll(&db.Token{}).SimpleFind(
+		&tokens,
+		bson.M{
+			"user":        userId,
+			"type":        db.TokenTypeRefresh,
+			"blacklisted": false,
+			"expires_at":  bson.M{"$gt": time.Now()},
+		},
+		options.Find().SetSort(bson.M{"created_at": -1}),
+	)
+	if err != nil {
+		return nil, errors.New("cannot find sessions")
+	}
+
+	return tokens, nil
+}
+
+// RevokeSession blacklists a refresh token session of the user with its access tokens
+func RevokeSession(userId primitive.ObjectID, sessionId primitive.ObjectID) error {
+	session := &db.Token{}
+	err := mgm.Coll(session).First(
+		bson.M{field.ID: sessionId, "user": userId, "type": db.TokenTypeRefresh, "blacklisted": false},
+		session,
+	)
+	if err != nil {
+		return errors.New("cannot find session")
+	}
+
+	if session.Family.IsZero() {
+		// tokens issued before token families were added
+		return BlacklistTokens(userId, session.ID)
+	}
+
+	return BlacklistTokenFamily(userId, session.Family)
+}
+
+// TouchSession updates last used time of the refresh token session which the access token belongs to,
+// it is written at most once in a minute per session
+func TouchSession(accessToken *db.Token) {
+	if accessToken.Family.IsZero() {
+		return
+	}
+
+	now := time.Now()
+	_, _ = mgm.Coll(&db.Token{}).UpdateOne(
+		mgm.Ctx(),
+		bson.M{
+			"user":        accessToken.User,
+			"family":      accessToken.Family,
+			"type":        db.TokenTypeRefresh,
+			"blacklisted": false,
+			"$or": bson.A{
+				bson.M{"last_used_at": bson.M{"$exists": false}},
+				bson.M{"last_used_at": bson.M{"$lt": now.Add(-time.Minute)}},
+			},
+		},
+		bson.M{"$set": bson.M{"last_used_at": now}},
+	)
+}
+
+// BlacklistTokens marks the given tokens of the user as blacklisted
+func BlacklistTokens(userId primitive.ObjectID, tokenIds ...primitive.ObjectID) error {
+	_, err := mgm.Coll(&db.Token{}).UpdateMany(
+		mgm.Ctx(),
+		bson.M{field.ID: bson.M{"$in": tokenIds}, "user": userId},
+		bson.M{"$set": bson.M{"blacklisted": true}},
+	)
+	if err != nil {
+		return errors.New("cannot revoke tokens")
+	}
+
+	return nil
+}
+
+// BlacklistUserTokens marks every token of the user as blacklisted
+func BlacklistUserTokens(userId primitive.ObjectID) error {
+	_, err := mgm.Coll(&db.Token{}).UpdateMany(
+		mgm.Ctx(),
+		bson.M{"user": userId, "blacklisted": false},
+		bson.M{"$set": bson.M{"blacklisted": true}},
+	)
+	if err != nil {
+		return errors.New("cannot revoke tokens")
+	}
+
+	return nil
+}
+
+// BlacklistOtherUserTokens marks every token of the user except the given family as blacklisted
+func BlacklistOtherUserTokens(userId primitive.ObjectID, family primitive.ObjectID) error {
+	_, err := mgm.Coll(&db.Token{}).UpdateMany(
+		mgm.Ctx(),
+		bson.M{"user": userId, "family": bson.M{"$ne": family}, "blacklisted": false},
+		bson.M{"$set": bson.M{"blacklisted": true}},
+	)
+	if err != nil {
+		return errors.New("cannot revoke tokens")
+	}
+
+	return nil
+}
+
+// BlacklistTokenFamily marks every token of a login session as blacklisted
+func BlacklistTokenFamily(userId primitive.ObjectID, family primitive.ObjectID) error {
+	_, err := mgm.Coll(&db.Token{}).UpdateMany(
+		mgm.Ctx(),
+		bson.M{"user": userId, "family": family, "blacklisted": false},
+		bson.M{"$set": bson.M{"blacklisted": true}},
+	)
+	if err != nil {
+		return errors.New("cannot revoke tokens")
+	}
+
+	return nil
+}
+
+// GenerateAccessTokens generates "access" and "refresh" token for user in a new token family
+func GenerateAccessTokens(user *db.User, device *db.TokenDevice) (*db.Token, *db.Token, error) {
+	return generateTokenPair(user, primitive.NewObjectID(), device)
+}
+
+// RotateAccessTokens revokes the given refresh token and generates a new token pair in the same family.
+// A refresh token can be rotated only once, presenting it again revokes the whole family.
+func RotateAccessTokens(user *db.User, refreshToken *db.Token, device *db.TokenDevice) (*db.Token, *db.Token, error) {
+	// rotated flag is set atomically, so concurrent refreshes with the same token count as reuse
+	updateResult, err := mgm.Coll(refreshToken).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: refreshToken.ID, "rotated": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"rotated": true, "blacklisted": true}},
+	)
+	if err != nil {
+		return nil, nil, errors.New("cannot rotate token")
+	}
+
+	if updateResult.ModifiedCount <= 0 {
+		return nil, nil, revokeReusedFamily(refreshToken)
+	}
+
+	family := refreshToken.Family
+	if family.IsZero() {
+		// tokens issued before token families were added
+		family = primitive.NewObjectID()
+	} else if err = BlacklistTokenFamily(user.ID, family); err != nil {
+		return nil, nil, err
+	}
+
+	// device name is sent on login, keep it for rotated tokens
+	if device.Name == "" && refreshToken.Device != nil {
+		device.Name = refreshToken.Device.Name
+	}
+
+	return generateTokenPair(user, family, device)
+}
+
+func generateTokenPair(user *db.User, family primitive.ObjectID, device *db.TokenDevice) (*db.Token, *db.Token, error) {
 	accessExpiresAt := time.Now().Add(time.Duration(Config.JWTAccessExpirationMinutes) * time.Minute)
 	refreshExpiresAt := time.Now().Add(time.Duration(Config.JWTRefreshExpirationDays) * time.Hour * 24)
 
-	accessToken, err := CreateToken(user, db.TokenTypeAccess, accessExpiresAt)
+	accessToken, err := CreateToken(user, family, db.TokenTypeAccess, accessExpiresAt, device)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	refreshToken, err := CreateToken(user, db.TokenTypeRefresh, refreshExpiresAt)
+	refreshToken, err := CreateToken(user, family, db.TokenTypeRefresh, refreshExpiresAt, device)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -67,30 +245,222 @@ func GenerateAccessTokens(user *db.User) (*db.Token, *db.Token, error) {
 	return accessToken, refreshToken, nil
 }
 
-// VerifyToken checks jwt validity, expire date, blacklisted
+// revokeReusedFamily revokes every token of a family whose rotated refresh token was presented again
+func revokeReusedFamily(refreshToken *db.Token) error {
+	log.Printf(
+		"[SECURITY] refresh token reuse detected, user: %s, family: %s, token: %s\n",
+		refreshToken.User.Hex(), refreshToken.Family.Hex(), refreshToken.ID.Hex(),
+	)
+
+	if !refreshToken.Family.IsZero() {
+		_ = BlacklistTokenFamily(refreshToken.User, refreshToken.Family)
+	}
+
+	return errors.New("refresh token is already used")
+}
+
+// VerifyToken checks jwt validity, expire date, blacklisted and returns the exact token record by jti
 func VerifyToken(token string, tokenType string) (*db.Token, error) {
+	tokenModel, err := findToken(token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenModel.Blacklisted {
+		return nil, ErrTokenRevoked
+	}
+
+	return tokenModel, nil
+}
+
+// VerifyRefreshToken works like VerifyToken, but presenting an already rotated
+// refresh token revokes its whole family
+func VerifyRefreshToken(token string) (*db.Token, error) {
+	tokenModel, err := findToken(token, db.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenModel.Rotated {
+		return nil, revokeReusedFamily(tokenModel)
+	}
+
+	if tokenModel.Blacklisted {
+		return nil, ErrTokenRevoked
+	}
+
+	return tokenModel, nil
+}
+
+// findToken checks jwt validity and expire date, returns the token record including blacklisted ones
+func findToken(token string, tokenType string) (*db.Token, error) {
+	if token == "" {
+		return nil, ErrTokenMissing
+	}
+
 	claims := &db.UserClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(Config.JWTSecretKey), nil
-	})
+	_, err := jwt.ParseWithClaims(token, claims, verificationKey)
+
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) {
+		switch {
+		case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+			return nil, ErrTokenMalformed
+		case validationErr.Errors&jwt.ValidationErrorExpired != 0:
+			return nil, ErrTokenExpired
+		}
+	}
 
 	if err != nil || claims.Type != tokenType {
-		return nil, errors.New("not valid token")
+		return nil, ErrTokenInvalid
 	}
 
-	if time.Now().Sub(claims.ExpiresAt.Time) > 10*time.Second {
-		return nil, errors.New("token is expired")
+	userId, _ := primitive.ObjectIDFromHex(claims.Subject)
+	tokenHash := hashToken(token)
+	filter := bson.M{"jti": claims.ID, "type": tokenType, "user": userId}
+	if claims.ID == "" {
+		// tokens issued before jti claim was added
+		delete(filter, "jti")
+		filter["hash"] = tokenHash
 	}
 
 	tokenModel := &db.Token{}
-	userId, _ := primitive.ObjectIDFromHex(claims.Subject)
-	err = mgm.Coll(tokenModel).First(
-		bson.M{"type": tokenType, "user": userId, "blacklisted": false},
+	err = mgm.Coll(tokenModel).First(filter, tokenModel)
+	if err != nil || subtle.ConstantTimeCompare([]byte(tokenModel.Hash), []byte(tokenHash)) != 1 {
+		// signature is valid, so the token was issued and its record is removed
+		return nil, ErrTokenRevoked
+	}
+	tokenModel.Claims = claims
+
+	return tokenModel, nil
+}
+
+// CreateOneTimeToken creates a random single use token, only its hash is saved
+func CreateOneTimeToken(userId primitive.ObjectID, tokenType string, expiresAt time.Time) (*db.Token, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, errors.New("cannot create token")
+	}
+	tokenString := base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	tokenModel := db.NewToken(userId, primitive.NilObjectID, "", hashToken(tokenString), tokenType, expiresAt)
+	err := mgm.Coll(tokenModel).Create(tokenModel)
+	if err != nil {
+		return nil, errors.New("cannot save token to db")
+	}
+	tokenModel.Token = tokenString
+
+	return tokenModel, nil
+}
+
+// UseOneTimeToken finds a not expired one time token and marks it as used
+func UseOneTimeToken(token string, tokenType string) (*db.Token, error) {
+	tokenModel := &db.Token{}
+	err := mgm.Coll(tokenModel).FindOneAndUpdate(
+		mgm.Ctx(),
+		bson.M{
+			"hash":        hashToken(token),
+			"type":        tokenType,
+			"blacklisted": false,
+			"expires_at":  bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": bson.M{"blacklisted": true}},
+	).Decode(tokenModel)
+	if err != nil {
+		return nil, errors.New("not valid token")
+	}
+
+	return tokenModel, nil
+}
+
+// FindOneTimeToken finds a not expired and not used one time token without using it
+func FindOneTimeToken(token string, tokenType string) (*db.Token, error) {
+	tokenModel := &db.Token{}
+	err := mgm.Coll(tokenModel).First(
+		bson.M{
+			"hash":        hashToken(token),
+			"type":        tokenType,
+			"blacklisted": false,
+			"expires_at":  bson.M{"$gt": time.Now()},
+		},
 		tokenModel,
 	)
 	if err != nil {
-		return nil, errors.New("cannot find token")
+		return nil, errors.New("not valid token")
 	}
 
 	return tokenModel, nil
 }
+
+// BlacklistUserTokensByType marks every token of a type for user as blacklisted
+func BlacklistUserTokensByType(userId primitive.ObjectID, tokenType string) error {
+	_, err := mgm.Coll(&db.Token{}).UpdateMany(
+		mgm.Ctx(),
+		bson.M{"user": userId, "type": tokenType, "blacklisted": false},
+		bson.M{"$set": bson.M{"blacklisted": true}},
+	)
+	if err != nil {
+		return errors.New("cannot revoke tokens")
+	}
+
+	return nil
+}
+
+// hashToken returns hex encoded SHA-256 digest of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrateTokenHashes replaces plain tokens saved by older versions with their hashes
+func MigrateTokenHashes() {
+	coll := mgm.Coll(&db.Token{})
+	cursor, err := coll.Find(mgm.Ctx(), bson.M{"token": bson.M{"$exists": true}})
+	if err != nil {
+		log.Println("cannot migrate token hashes:", err)
+		return
+	}
+	defer cursor.Close(mgm.Ctx())
+
+	migrated := 0
+	for cursor.Next(mgm.Ctx()) {
+		var legacy struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Token string             `bson:"token"`
+		}
+		if err = cursor.Decode(&legacy); err != nil {
+			continue
+		}
+
+		_, err = coll.UpdateOne(
+			mgm.Ctx(),
+			bson.M{field.ID: legacy.ID},
+			bson.M{"$set": bson.M{"hash": hashToken(legacy.Token)}, "$unset": bson.M{"token": ""}},
+		)
+		if err == nil {
+			migrated++
+		}
+	}
+
+	if migrated > 0 {
+		log.Printf("Migrated %d plain tokens to hashes\n", migrated)
+	}
+}
+
+// EnsureTokenIndexes creates indexes of token lookups by jti claim and hash
+func EnsureTokenIndexes() {
+	_, err := mgm.Coll(&db.Token{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "jti", Value: 1}},
+			Options: options.Index().SetName("tokens_jti"),
+		},
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetName("tokens_hash"),
+		},
+	})
+
+	if err != nil {
+		log.Println("cannot create token indexes:", err)
+	}
+}
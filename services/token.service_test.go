@@ -0,0 +1,77 @@
+package services
+
+import (
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"testing"
+)
+
+func TestRotateAccessTokensReuseRevokesFamily(t *testing.T) {
+	useTestDatabase(t)
+
+	user, err := CreateUser("Test", "rotate@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, refreshToken, err := GenerateAccessTokens(user, &db.TokenDevice{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	presented, err := VerifyRefreshToken(refreshToken.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAccessToken, newRefreshToken, err := RotateAccessTokens(user, presented, &db.TokenDevice{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newRefreshToken.Family != refreshToken.Family {
+		t.Fatal("expected rotated tokens to stay in the same family")
+	}
+	if _, err = VerifyToken(newAccessToken.Token, db.TokenTypeAccess); err != nil {
+		t.Fatalf("expected rotated access token to be valid, got %v", err)
+	}
+
+	// the rotated refresh token is presented again, e.g. by an attacker who stole it
+	if _, err = VerifyRefreshToken(refreshToken.Token); err == nil {
+		t.Fatal("expected rotated refresh token to be rejected")
+	}
+
+	if _, err = VerifyToken(newAccessToken.Token, db.TokenTypeAccess); err != ErrTokenRevoked {
+		t.Fatalf("expected access token of the family to be revoked, got %v", err)
+	}
+	if _, err = VerifyRefreshToken(newRefreshToken.Token); err != ErrTokenRevoked {
+		t.Fatalf("expected refresh token of the family to be revoked, got %v", err)
+	}
+}
+
+func TestRotateAccessTokensConcurrentReuse(t *testing.T) {
+	useTestDatabase(t)
+
+	user, err := CreateUser("Test", "concurrent-rotate@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, refreshToken, err := GenerateAccessTokens(user, &db.TokenDevice{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both requests verify the refresh token before any of them rotates it
+	first, _ := VerifyRefreshToken(refreshToken.Token)
+	second, _ := VerifyRefreshToken(refreshToken.Token)
+
+	_, rotated, err := RotateAccessTokens(user, first, &db.TokenDevice{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = RotateAccessTokens(user, second, &db.TokenDevice{}); err == nil {
+		t.Fatal("expected second rotation to be rejected")
+	}
+
+	if _, err = VerifyRefreshToken(rotated.Token); err != ErrTokenRevoked {
+		t.Fatalf("expected family to be revoked, got %v", err)
+	}
+}
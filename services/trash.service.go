@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+const trashPurgeInterval = time.Hour
+
+// GetTrashedNotes get paginated notes in trash, recently deleted notes are first
+func GetTrashedNotes(userId primitive.ObjectID, page int, limit int) ([]db.Note, error) {
+	var notes []db.Note
+
+	findOptions := options.Find().
+		SetSort(bson.M{"deleted_at": -1}).
+		SetSkip(int64(page * limit)).
+		SetLimit(int64(limit + 1))
+
+	err := mgm.Coll(&db.Note{}).SimpleFind(
+		&notes,
+		bson.M{"author": userId, "deleted_at": bson.M{"$ne": nil}},
+		findOptions,
+	)
+
+	if err != nil {
+		return nil, errors.New("cannot find notes")
+	}
+
+	return notes, nil
+}
+
+// RestoreNote moves a note out of trash, it is moved out of notebooks if its notebook is deleted
+func RestoreNote(userId primitive.ObjectID, noteId primitive.ObjectID) (*db.Note, error) {
+	note := &db.Note{}
+	err := mgm.Coll(note).First(bson.M{field.ID: noteId, "author": userId, "deleted_at": bson.M{"$ne": nil}}, note)
+	if err != nil {
+		return nil, errors.New("cannot find note in trash")
+	}
+
+	note.DeletedAt = nil
+	update := bson.M{"$unset": bson.M{"deleted_at": ""}}
+	if note.Notebook != nil {
+		if _, err = GetNotebookById(userId, *note.Notebook); err != nil {
+			note.Notebook = nil
+			update = bson.M{"$unset": bson.M{"deleted_at": "", "notebook_id": ""}}
+		}
+	}
+
+	_, err = mgm.Coll(note).UpdateOne(mgm.Ctx(), bson.M{field.ID: note.ID}, update)
+	if err != nil {
+		return nil, errors.New("cannot restore note")
+	}
+
+	return note, nil
+}
+
+// DeleteTrashedNote deletes a note in trash permanently
+func DeleteTrashedNote(userId primitive.ObjectID, noteId primitive.ObjectID) error {
+	deletedCount, err := deleteNotes(bson.M{field.ID: noteId, "author": userId, "deleted_at": bson.M{"$ne": nil}})
+
+	if err != nil || deletedCount <= 0 {
+		return errors.New("cannot delete note")
+	}
+
+	return nil
+}
+
+// EmptyTrash deletes all notes in trash of the user permanently, returns count of deleted notes
+func EmptyTrash(userId primitive.ObjectID) (int64, error) {
+	deletedCount, err := deleteNotes(bson.M{"author": userId, "deleted_at": bson.M{"$ne": nil}})
+	if err != nil {
+		return 0, errors.New("cannot empty trash")
+	}
+
+	return deletedCount, nil
+}
+
+// PurgeTrash deletes notes which are in trash longer than TRASH_RETENTION_DAYS
+func PurgeTrash() (int64, error) {
+	if Config.TrashRetentionDays <= 0 {
+		return 0, nil
+	}
+
+	deletedBefore := time.Now().AddDate(0, 0, -Config.TrashRetentionDays)
+	deletedCount, err := deleteNotes(bson.M{"deleted_at": bson.M{"$lt": deletedBefore}})
+	if err != nil {
+		return 0, errors.New("cannot purge trash")
+	}
+
+	return deletedCount, nil
+}
+
+// StartTrashPurgeJob purges trash on start and then every hour in background
+func StartTrashPurgeJob() {
+	if Config.TrashRetentionDays <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := PurgeTrash()
+			if err != nil {
+				log.Println(err)
+			} else if purged > 0 {
+				log.Printf("Purged %d notes from trash\n", purged)
+			}
+
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEmptyTrashKeepsOtherNotesAndVersions(t *testing.T) {
+	useTestDatabase(t)
+
+	userId := primitive.NewObjectID()
+	trashed, err := CreateNote(userId, "trashed", "first", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := CreateNote(userId, "kept", "first", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, note := range []*db.Note{trashed, kept} {
+		if err = UpdateNote(userId, note.ID, &models.NoteRequest{Title: note.Title, Content: "second"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = DeleteNote(userId, trashed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deletedCount, err := EmptyTrash(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deletedCount != 1 {
+		t.Fatalf("expected 1 deleted note, got %d", deletedCount)
+	}
+
+	if _, err = GetNoteById(userId, kept.ID); err != nil {
+		t.Fatal("expected note out of trash to be kept")
+	}
+	versionCount := func(noteId primitive.ObjectID) int64 {
+		count, err := mgm.Coll(&db.NoteVersion{}).CountDocuments(mgm.Ctx(), bson.M{"note": noteId})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	if versionCount(trashed.ID) != 0 {
+		t.Fatal("expected versions of deleted note to be deleted")
+	}
+	if versionCount(kept.ID) != 1 {
+		t.Fatal("expected versions of kept note to be kept")
+	}
+}
+
+func TestPurgeTrashDeletesOnlyExpiredNotes(t *testing.T) {
+	useTestDatabase(t)
+
+	userId := primitive.NewObjectID()
+	expired, _ := CreateNote(userId, "expired", "content", nil, nil)
+	recent, _ := CreateNote(userId, "recent", "content", nil, nil)
+	_, err := mgm.Coll(expired).UpdateByID(mgm.Ctx(), expired.ID, bson.M{"$set": bson.M{"deleted_at": time.Now().AddDate(0, 0, -31)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = DeleteNote(userId, recent.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := PurgeTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 purged note, got %d", purged)
+	}
+	if _, err = RestoreNote(userId, recent.ID); err != nil {
+		t.Fatal("expected recently deleted note to be kept in trash")
+	}
+}
+
+func TestExcludeIds(t *testing.T) {
+	a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	if remaining := excludeIds([]primitive.ObjectID{a, b, c}, []primitive.ObjectID{b}); !reflect.DeepEqual(remaining, []primitive.ObjectID{a, c}) {
+		t.Fatalf("expected excluded id to be removed by keeping the order, got %v", remaining)
+	}
+	if remaining := excludeIds([]primitive.ObjectID{a}, nil); !reflect.DeepEqual(remaining, []primitive.ObjectID{a}) {
+		t.Fatalf("expected ids to be kept, got %v", remaining)
+	}
+}
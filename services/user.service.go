@@ -2,21 +2,26 @@ package services
 
 import (
 	"errors"
+	"github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models"
 	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"sync"
 )
 
 // CreateUser create a user record
 func CreateUser(name string, email string, plainPassword string) (*db.User, error) {
-	password, err := bcrypt.GenerateFromPassword([]byte(plainPassword), bcrypt.DefaultCost)
+	password, err := hashPassword(plainPassword)
 	if err != nil {
-		return nil, errors.New("cannot generate hashed password")
+		return nil, err
 	}
 
-	user := db.NewUser(email, string(password), name, db.RoleUser)
+	user := db.NewUser(email, password, name, db.RoleUser)
 	err = mgm.Coll(user).Create(user)
 	if err != nil {
 		return nil, errors.New("cannot create new user")
@@ -25,6 +30,54 @@ func CreateUser(name string, email string, plainPassword string) (*db.User, erro
 	return user, nil
 }
 
+// CreateExternalUser creates a user without password for an OIDC identity, email is verified by the provider
+func CreateExternalUser(name string, email string, identity db.UserIdentity) (*db.User, error) {
+	user := db.NewUser(email, "", name, db.RoleUser)
+	user.MailVerified = true
+	user.Identities = []db.UserIdentity{identity}
+
+	err := mgm.Coll(user).Create(user)
+	if err != nil {
+		return nil, errors.New("cannot create new user")
+	}
+
+	return user, nil
+}
+
+// LinkUserIdentity links an OIDC identity to the user. If email of the user was not verified,
+// the account may be registered by someone else, so its password, mfa, tokens and api keys are removed.
+func LinkUserIdentity(user *db.User, identity db.UserIdentity) error {
+	update := bson.M{
+		"$push": bson.M{"identities": identity},
+		"$set":  bson.M{"mail_verified": true},
+	}
+	if !user.MailVerified {
+		update["$set"] = bson.M{"mail_verified": true, "password": "", "mfa_enabled": false}
+		update["$unset"] = bson.M{"mfa_secret": "", "mfa_last_step": "", "mfa_recovery_codes": ""}
+	}
+
+	_, err := mgm.Coll(user).UpdateOne(mgm.Ctx(), bson.M{field.ID: user.ID}, update)
+	if err != nil {
+		return errors.New("cannot link account")
+	}
+
+	if !user.MailVerified {
+		_ = RevokeUserApiKeys(user.ID)
+		if err = BlacklistUserTokens(user.ID); err != nil {
+			return err
+		}
+
+		user.Password = ""
+		user.MFAEnabled = false
+		user.MFASecret = ""
+		user.MFARecoveryCodes = nil
+	}
+
+	user.MailVerified = true
+	user.Identities = append(user.Identities, identity)
+	return nil
+}
+
 // FindUserById find user by id
 func FindUserById(userId primitive.ObjectID) (*db.User, error) {
 	user := &db.User{}
@@ -58,3 +111,178 @@ func CheckUserMail(email string) error {
 
 	return nil
 }
+
+// UpdateUserPassword hashes and saves a new password for user
+func UpdateUserPassword(user *db.User, plainPassword string) error {
+	err := CheckUserNewPassword(user, plainPassword)
+	if err != nil {
+		return err
+	}
+
+	password, err := hashPassword(plainPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID},
+		bson.M{"$set": bson.M{"password": password}},
+	)
+	if err != nil {
+		return errors.New("cannot update password")
+	}
+
+	user.Password = password
+	return nil
+}
+
+// CheckUserNewPassword checks the password policy with email and name of the user,
+// requests without the user like reset password cannot check them in validation
+func CheckUserNewPassword(user *db.User, plainPassword string) error {
+	err := models.CheckPassword(plainPassword, user.Email, user.Name)
+	if err != nil {
+		return validation.Errors{"password": err}
+	}
+
+	return nil
+}
+
+// SetUserRole changes role of the user and revokes its tokens, so new tokens carry the new role.
+// Api keys are kept, their role is read from db on every request and limits their scopes.
+func SetUserRole(user *db.User, role string) error {
+	if !db.IsValidRole(role) {
+		return errors.New("invalid role: " + role)
+	}
+
+	_, err := mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID},
+		bson.M{"$set": bson.M{"role": role}},
+	)
+	if err != nil {
+		return errors.New("cannot update role")
+	}
+
+	user.Role = role
+	return BlacklistUserTokens(user.ID)
+}
+
+// GetUsers get paginated user list, query searches in email and name
+func GetUsers(query string, page int, limit int) ([]db.User, error) {
+	var users []db.User
+
+	filter := bson.M{}
+	if query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+		filter["$or"] = bson.A{bson.M{"email": pattern}, bson.M{"name": pattern}}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64(page * limit)).
+		SetLimit(int64(limit + 1))
+
+	err := mgm.Coll(&db.User{}).SimpleFind(&users, filter, findOptions)
+	if err != nil {
+		return nil, errors.New("cannot find users")
+	}
+
+	return users, nil
+}
+
+// SetUserDisabled disables or enables a user, disabled users lose their tokens
+func SetUserDisabled(user *db.User, disabled bool) error {
+	_, err := mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID},
+		bson.M{"$set": bson.M{"disabled": disabled}},
+	)
+	if err != nil {
+		return errors.New("cannot update user")
+	}
+
+	user.Disabled = disabled
+	if disabled {
+		return BlacklistUserTokens(user.ID)
+	}
+
+	return nil
+}
+
+// DeleteUser deletes a user with its notes, notebooks, tokens and api keys
+func DeleteUser(userId primitive.ObjectID) error {
+	deleteResult, err := mgm.Coll(&db.User{}).DeleteOne(mgm.Ctx(), bson.M{field.ID: userId})
+	if err != nil || deleteResult.DeletedCount <= 0 {
+		return errors.New("cannot delete user")
+	}
+
+	_, _ = mgm.Coll(&db.Note{}).DeleteMany(mgm.Ctx(), bson.M{"author": userId})
+	_, _ = mgm.Coll(&db.NoteVersion{}).DeleteMany(mgm.Ctx(), bson.M{"author": userId})
+	_, _ = mgm.Coll(&db.Notebook{}).DeleteMany(mgm.Ctx(), bson.M{"author": userId})
+	_, _ = mgm.Coll(&db.Token{}).DeleteMany(mgm.Ctx(), bson.M{"user": userId})
+	_, _ = mgm.Coll(&db.ApiKey{}).DeleteMany(mgm.Ctx(), bson.M{"user": userId})
+
+	return nil
+}
+
+// CheckUserPassword compares plain password with user's hashed password, algorithm is detected from the hash
+func CheckUserPassword(user *db.User, plainPassword string) bool {
+	hasher, ok := findPasswordHasher(user.Password)
+	if !ok {
+		// users without password, e.g. created with OIDC login
+		return false
+	}
+
+	return hasher.Verify(user.Password, plainPassword)
+}
+
+// RehashUserPassword upgrades the stored hash to the current hasher and parameters,
+// it has to be called after a successful password check
+func RehashUserPassword(user *db.User, plainPassword string) {
+	if !passwordNeedsRehash(user.Password) {
+		return
+	}
+
+	password, err := hashPassword(plainPassword)
+	if err != nil {
+		return
+	}
+
+	// the hash is replaced only if the password is not changed meanwhile
+	updateResult, err := mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID, "password": user.Password},
+		bson.M{"$set": bson.M{"password": password}},
+	)
+	if err == nil && updateResult.ModifiedCount > 0 {
+		user.Password = password
+	}
+}
+
+// passwordNeedsRehash reports whether the hash is created by another hasher or with other parameters
+func passwordNeedsRehash(encodedHash string) bool {
+	hasher := GetPasswordHasher()
+	return !hasher.Identify(encodedHash) || hasher.NeedsRehash(encodedHash)
+}
+
+var dummyPassword string
+var dummyPasswordOnce sync.Once
+
+// CheckDummyPassword spends the same time as CheckUserPassword, used when user cannot be found
+func CheckDummyPassword(plainPassword string) {
+	dummyPasswordOnce.Do(func() {
+		dummyPassword, _ = GetPasswordHasher().Hash("dummy-password")
+	})
+
+	_ = GetPasswordHasher().Verify(dummyPassword, plainPassword)
+}
+
+func hashPassword(plainPassword string) (string, error) {
+	password, err := GetPasswordHasher().Hash(plainPassword)
+	if err != nil {
+		return "", errors.New("cannot generate hashed password")
+	}
+
+	return password, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	db "github.com/ebubekiryigit/golang-mongodb-rest-api-starter/models/db"
+	"github.com/kamva/mgm/v3"
+	"github.com/kamva/mgm/v3/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+var ErrMailThrottled = errors.New("please wait before requesting a new verification email")
+
+// SendVerificationMail revokes previous verification tokens and mails a new one
+func SendVerificationMail(user *db.User) error {
+	return sendVerificationMail(user, 0)
+}
+
+// ResendVerificationMail sends a new verification mail if the last one is old enough
+func ResendVerificationMail(user *db.User) error {
+	if user.MailVerified {
+		return errors.New("email is already verified")
+	}
+
+	return sendVerificationMail(user, time.Duration(Config.VerifyEmailResendSeconds)*time.Second)
+}
+
+func sendVerificationMail(user *db.User, throttle time.Duration) error {
+	// concurrent requests cannot both pass the throttle, only one of them updates the send time
+	now := time.Now()
+	updateResult, err := mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{
+			field.ID: user.ID,
+			"$or": bson.A{
+				bson.M{"verify_mail_sent_at": bson.M{"$exists": false}},
+				bson.M{"verify_mail_sent_at": bson.M{"$lte": now.Add(-throttle)}},
+			},
+		},
+		bson.M{"$set": bson.M{"verify_mail_sent_at": now}},
+	)
+	if err != nil {
+		return errors.New("cannot send verification email")
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrMailThrottled
+	}
+
+	_ = BlacklistUserTokensByType(user.ID, db.TokenTypeVerifyEmail)
+
+	expiresAt := now.Add(time.Duration(Config.VerifyEmailExpirationHours) * time.Hour)
+	token, err := CreateOneTimeToken(user.ID, db.TokenTypeVerifyEmail, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	body := "Hi " + user.Name + ",\n\n" +
+		"Please verify your email address by opening the link below:\n\n" +
+		Config.AppUrl + "/verify-email?token=" + token.Token + "\n\n" +
+		"The link expires at " + expiresAt.Format("2006-01-02 15:04:05") + "."
+
+	err = SendMail(user.Email, "Verify your email address", body)
+	if err != nil {
+		return errors.New("cannot send verification email")
+	}
+
+	return nil
+}
+
+// VerifyUserMail uses a verification token and marks its user's email as verified
+func VerifyUserMail(token string) (*db.User, error) {
+	tokenModel, err := UseOneTimeToken(token, db.TokenTypeVerifyEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := FindUserById(tokenModel.User)
+	if err != nil {
+		return nil, err
+	}
+
+	user.MailVerified = true
+	_, err = mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID},
+		bson.M{"$set": bson.M{"mail_verified": true}},
+	)
+	if err != nil {
+		return nil, errors.New("cannot verify email")
+	}
+
+	return user, nil
+}
+
+// SendEmailChangeMail mails a confirmation token to the new email, email is changed after confirmation
+func SendEmailChangeMail(user *db.User, newEmail string) error {
+	err := CheckUserMail(newEmail)
+	if err != nil {
+		return err
+	}
+
+	_ = BlacklistUserTokensByType(user.ID, db.TokenTypeChangeEmail)
+
+	expiresAt := time.Now().Add(time.Duration(Config.VerifyEmailExpirationHours) * time.Hour)
+	token, err := CreateOneTimeToken(user.ID, db.TokenTypeChangeEmail, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = mgm.Coll(token).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: token.ID},
+		bson.M{"$set": bson.M{"email": newEmail}},
+	)
+	if err != nil {
+		return errors.New("cannot save token to db")
+	}
+
+	body := "Hi " + user.Name + ",\n\n" +
+		"Please confirm your new email address by opening the link below:\n\n" +
+		Config.AppUrl + "/confirm-email-change?token=" + token.Token + "\n\n" +
+		"The link expires at " + expiresAt.Format("2006-01-02 15:04:05") + "."
+
+	err = SendMail(newEmail, "Confirm your new email address", body)
+	if err != nil {
+		return errors.New("cannot send confirmation email")
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange uses a change email token and sets the new email as verified
+func ConfirmEmailChange(token string) (*db.User, error) {
+	tokenModel, err := UseOneTimeToken(token, db.TokenTypeChangeEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	// email might be taken after the token was sent
+	err = CheckUserMail(tokenModel.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := FindUserById(tokenModel.User)
+	if err != nil {
+		return nil, err
+	}
+
+	oldEmail := user.Email
+	user.Email = tokenModel.Email
+	user.MailVerified = true
+	_, err = mgm.Coll(user).UpdateOne(
+		mgm.Ctx(),
+		bson.M{field.ID: user.ID},
+		bson.M{"$set": bson.M{"email": user.Email, "mail_verified": true}},
+	)
+	if err != nil {
+		return nil, errors.New("cannot change email")
+	}
+
+	go SendMail(oldEmail, "Your email address was changed",
+		"Hi "+user.Name+",\n\nYour account email was changed to "+user.Email+". "+
+			"If you didn't make this change, please contact us.")
+
+	return user, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestResendVerificationMailThrottle(t *testing.T) {
+	useTestDatabase(t)
+	mails := useTestMailer(t)
+
+	user, err := CreateUser("Test", "throttle@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = SendVerificationMail(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ResendVerificationMail(user); err != ErrMailThrottled {
+		t.Fatalf("expected resend right after register to be throttled, got %v", err)
+	}
+	if mails.count() != 1 {
+		t.Fatalf("expected 1 mail, got %d", mails.count())
+	}
+}
+
+func TestResendVerificationMailConcurrent(t *testing.T) {
+	useTestDatabase(t)
+	mails := useTestMailer(t)
+
+	user, err := CreateUser("Test", "concurrent@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = ResendVerificationMail(user)
+		}()
+	}
+	wg.Wait()
+
+	if mails.count() != 1 {
+		t.Fatalf("expected only one of concurrent resends to send a mail, got %d", mails.count())
+	}
+}